@@ -13,28 +13,11 @@
 package main
 
 import (
-	"log"
+	"os"
 
-	"github.com/antsanchez/go-download-web/pkg/console"
-	"github.com/antsanchez/go-download-web/pkg/get"
-	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/antsanchez/go-download-web/pkg/cli"
 )
 
 func main() {
-
-	// Parse the flags
-	conf, err := scraper.ParseFlags()
-	if err != nil {
-		log.Println(err)
-		return
-	}
-
-	// Create a new scraper
-	scrap, err := scraper.New(conf, get.New(), console.New())
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Run the scraper
-	scrap.Run()
+	os.Exit(cli.Run(os.Args[1:]))
 }
@@ -0,0 +1,96 @@
+// Package cli runs the commands of go-download-web
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/antsanchez/go-download-web/pkg/diff"
+	"github.com/antsanchez/go-download-web/pkg/serve"
+	"github.com/antsanchez/go-download-web/pkg/verify"
+)
+
+// Exit codes of the commands. The ones finding problems, like broken links,
+// exit with ExitBroken if they find any.
+const (
+	ExitOK     = 0
+	ExitBroken = 1
+	ExitUsage  = 2
+	ExitError  = 3
+)
+
+// Command model, a subcommand of the program
+type Command struct {
+	Name    string
+	Summary string
+
+	// Run runs the command with the given arguments, returning its exit code
+	Run func(args []string) int
+}
+
+// Commands of the program, in the order they are listed on the help
+var Commands = []Command{
+	{Name: "crawl", Summary: "Download a site and save it locally", Run: crawlCommand},
+	{Name: "check", Summary: "Check the links of a site for broken ones", Run: checkCommand},
+	{Name: "serve", Summary: "Serve a downloaded site locally", Run: serve.Command},
+	{Name: "verify", Summary: "Check a downloaded site for missing files and old domain references", Run: verify.Command},
+	{Name: "diff", Summary: "Compare two runs", Run: diff.Command},
+	{Name: "export", Summary: "Export the redirects of a run to another format", Run: exportCommand},
+}
+
+// Run runs the command of the given arguments, returning its exit code.
+// Arguments starting with a flag, like "-u URL", run the crawl command.
+func Run(args []string) int {
+	if len(args) == 0 {
+		Usage(os.Stderr)
+		return ExitUsage
+	}
+
+	name := args[0]
+	switch {
+	case name == "help" || name == "-h" || name == "-help" || name == "--help":
+		if len(args) > 1 {
+			if command, ok := find(args[1]); ok {
+				return command.Run([]string{"-h"})
+			}
+		}
+		Usage(os.Stdout)
+		return 0
+	case strings.HasPrefix(name, "-"):
+		return crawlCommand(args)
+	}
+
+	command, ok := find(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", name)
+		Usage(os.Stderr)
+		return ExitUsage
+	}
+
+	return command.Run(args[1:])
+}
+
+// find returns the command of the given name
+func find(name string) (Command, bool) {
+	for _, command := range Commands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+	return Command{}, false
+}
+
+// Usage prints the commands of the program
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: ./go-download-web <command> [options]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, command := range Commands {
+		fmt.Fprintf(w, "  %-8s %s\n", command.Name, command.Summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run ./go-download-web <command> -h for the options of a command.")
+	fmt.Fprintln(w, "./go-download-web -u <URL> [options] is the same as ./go-download-web crawl -u <URL> [options].")
+}
@@ -0,0 +1,41 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/cli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	assert.Equal(t, 0, cli.Run([]string{"help"}))
+	assert.Equal(t, 0, cli.Run([]string{"help", "export"}))
+	assert.Equal(t, 0, cli.Run([]string{"crawl", "-h"}))
+	assert.Equal(t, 0, cli.Run([]string{"-h"}))
+	assert.Equal(t, cli.ExitUsage, cli.Run(nil))
+	assert.Equal(t, cli.ExitUsage, cli.Run([]string{"unknown"}))
+
+	// Missing -u, on the command and on its alias
+	assert.Equal(t, cli.ExitUsage, cli.Run([]string{"crawl", "-s", "2"}))
+	assert.Equal(t, cli.ExitUsage, cli.Run([]string{"-s", "2"}))
+	assert.Equal(t, cli.ExitUsage, cli.Run([]string{"check", "-path", "./website"}))
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	redirects := `[{"from": "/old/", "to": "/new/", "status": 301, "chain": ["https://example.com/old/", "https://example.com/new/"]}]`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "redirects.json"), []byte(redirects), 0644))
+
+	output := filepath.Join(dir, "_redirects")
+	assert.Equal(t, cli.ExitOK, cli.Run([]string{"export", "-path", dir, "-format", "netlify", "-o", output}))
+
+	content, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, "/old/ /new/ 301\n", string(content))
+
+	assert.Equal(t, cli.ExitUsage, cli.Run([]string{"export", "-path", dir}))
+	assert.Equal(t, cli.ExitUsage, cli.Run([]string{"export", "-path", dir, "-format", "iis"}))
+	assert.Equal(t, cli.ExitError, cli.Run([]string{"export", "-path", t.TempDir(), "-format", "nginx"}))
+}
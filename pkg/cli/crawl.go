@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/antsanchez/go-download-web/pkg/console"
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/antsanchez/go-download-web/pkg/metrics"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/antsanchez/go-download-web/pkg/transform"
+)
+
+// crawlCommand downloads a site
+func crawlCommand(args []string) int {
+	return runScraper("crawl", args)
+}
+
+// checkCommand checks the links of a site, without saving anything
+func checkCommand(args []string) int {
+	return runScraper("check", args)
+}
+
+// runScraper parses the arguments of the given command and runs the scraper
+// with them
+func runScraper(command string, args []string) int {
+	conf, err := scraper.ParseArgs(command, args)
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	if err != nil {
+		log.Println(err)
+		return ExitUsage
+	}
+
+	// Select the console
+	con, err := console.Select(conf.Console, conf.ConsoleLevel)
+	if err != nil {
+		log.Println(err)
+		return ExitUsage
+	}
+
+	observers := []scraper.Observer{con}
+
+	// Leave the terminal as it was, even if the run doesn't start
+	if tui, ok := con.(*console.TUI); ok {
+		defer tui.Close()
+	}
+
+	// Serve the metrics of the run
+	if conf.Metrics != "" {
+		m := metrics.New()
+		if err := metrics.Serve(conf.Metrics, m); err != nil {
+			log.Println(err)
+			return ExitError
+		}
+		observers = append(observers, m)
+	}
+
+	// Load the transformers of the pages and files
+	var transformers []scraper.Transformer
+	if conf.Transform != "" {
+		transformers, err = transform.Load(conf.Transform)
+		if err != nil {
+			log.Println(err)
+			return ExitError
+		}
+	}
+
+	// Create a new scraper
+	scrap, err := scraper.NewFromConfig(conf, get.New(conf.Middlewares()...), observers...)
+	if err != nil {
+		log.Println(err)
+		return ExitError
+	}
+
+	scrap.Transformers = transformers
+
+	// Pause, resume and stop the scraper with the keys of the TUI
+	if tui, ok := con.(*console.TUI); ok {
+		tui.Control(scrap)
+	}
+
+	// Stop the scraper on the first interrupt or termination, saving what
+	// was fetched, and exit on the next one
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// Run the scraper
+	result, err := scrap.Run(ctx)
+	if err != nil {
+		log.Println(err)
+		return ExitError
+	}
+
+	// Without an output file, the broken links report goes to the standard
+	// output
+	if scrap.Check && conf.CheckOutput == "" {
+		if err := scrap.WriteCheckReport(os.Stdout, conf.CheckFormat); err != nil {
+			log.Println(err)
+			return ExitError
+		}
+	}
+
+	// Fail if any broken link was found
+	if len(result.Broken) > 0 {
+		return ExitBroken
+	}
+
+	return ExitOK
+}
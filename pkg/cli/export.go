@@ -0,0 +1,72 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// exportCommand exports the redirects found on a previous run, saved with
+// -redirects json or csv, to another format
+func exportCommand(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	downloadPath := flags.String("path", "./website", "Local path the site was downloaded to, with its redirects.json or redirects.csv")
+	from := flags.String("from", "", "redirects.json or redirects.csv file to export (default: the one of the download path)")
+	format := flags.String("format", "", "Format to export the redirects to: "+strings.Join(scraper.RedirectsFormats, ", ")+" (required)")
+	output := flags.String("o", "", "File to write the redirects to (default: standard output)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ./go-download-web export -format <FORMAT> [options]")
+		fmt.Fprintln(flags.Output(), "Exports the redirects found on a run, saved with -redirects json or csv, to another format.")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	if flags.NArg() != 0 || *format == "" {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	if !scraper.IsInSlice(*format, scraper.RedirectsFormats) {
+		fmt.Fprintf(os.Stderr, "invalid redirects format: -format (must be one of %s)\n", strings.Join(scraper.RedirectsFormats, ", "))
+		return ExitUsage
+	}
+
+	source := *from
+	if source == "" {
+		source = *downloadPath
+	}
+
+	rules, err := scraper.LoadRedirects(source)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading the redirects: %s\n", err)
+		return ExitError
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitError
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := scraper.WriteRedirects(w, *format, rules); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	return ExitOK
+}
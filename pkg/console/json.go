@@ -0,0 +1,70 @@
+package console
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// Event model, a line of the JSON console
+type Event struct {
+	Time        time.Time `json:"time"`
+	Event       string    `json:"event"`
+	URL         string    `json:"url,omitempty"`
+	Referrer    string    `json:"referrer,omitempty"`
+	Depth       int       `json:"depth,omitempty"`
+	Status      int       `json:"status,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	Bytes       int64     `json:"bytes,omitempty"`
+	DurationMs  float64   `json:"duration_ms,omitempty"`
+	Target      string    `json:"target,omitempty"`
+	Path        string    `json:"path,omitempty"`
+	Message     string    `json:"message,omitempty"`
+	Error       string    `json:"error,omitempty"`
+
+	// Counters of the run after the event
+	Stats Stats `json:"stats"`
+}
+
+// JSON is a console that prints every event as a JSON object per line, with
+// the counters of the run after it
+type JSON struct {
+	encoder *json.Encoder
+	counters
+}
+
+// NewJSON creates a JSON console printing on the given writer
+func NewJSON(out io.Writer) *JSON {
+	return &JSON{encoder: json.NewEncoder(out), counters: newCounters()}
+}
+
+// Notify prints the event, after applying it to the counters
+func (j *JSON) Notify(e scraper.Event) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.apply(e)
+
+	line := Event{
+		Time:        e.Time,
+		Event:       string(e.Type),
+		URL:         e.URL,
+		Referrer:    e.Referrer,
+		Depth:       e.Depth,
+		Status:      e.Status,
+		ContentType: e.ContentType,
+		Bytes:       e.Size,
+		DurationMs:  float64(e.Duration.Microseconds()) / 1000,
+		Target:      e.Target,
+		Path:        e.Path,
+		Message:     e.Message,
+		Stats:       j.stats,
+	}
+	if e.Err != nil {
+		line.Error = e.Err.Error()
+	}
+
+	j.encoder.Encode(line)
+}
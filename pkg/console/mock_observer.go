@@ -0,0 +1,52 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/antsanchez/go-download-web/pkg/scraper (interfaces: Observer)
+//
+// Generated by this command:
+//
+//	mockgen -destination=pkg/console/mock_observer.go -package=console github.com/antsanchez/go-download-web/pkg/scraper Observer
+//
+
+// Package console is a generated GoMock package.
+package console
+
+import (
+	reflect "reflect"
+
+	scraper "github.com/antsanchez/go-download-web/pkg/scraper"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockObserver is a mock of Observer interface.
+type MockObserver struct {
+	ctrl     *gomock.Controller
+	recorder *MockObserverMockRecorder
+}
+
+// MockObserverMockRecorder is the mock recorder for MockObserver.
+type MockObserverMockRecorder struct {
+	mock *MockObserver
+}
+
+// NewMockObserver creates a new mock instance.
+func NewMockObserver(ctrl *gomock.Controller) *MockObserver {
+	mock := &MockObserver{ctrl: ctrl}
+	mock.recorder = &MockObserverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockObserver) EXPECT() *MockObserverMockRecorder {
+	return m.recorder
+}
+
+// Notify mocks base method.
+func (m *MockObserver) Notify(arg0 scraper.Event) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Notify", arg0)
+}
+
+// Notify indicates an expected call of Notify.
+func (mr *MockObserverMockRecorder) Notify(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Notify", reflect.TypeOf((*MockObserver)(nil).Notify), arg0)
+}
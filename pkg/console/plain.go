@@ -0,0 +1,87 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// Level of a line of the plain console
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Names of the levels, as they are printed and parsed
+var levelNames = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
+// ParseLevel returns the level of the given name, case insensitive
+func ParseLevel(name string) (Level, error) {
+	for i, level := range levelNames {
+		if strings.EqualFold(name, level) {
+			return Level(i), nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("unknown level: %s (must be debug, info, warn or error)", name)
+}
+
+// String returns the name of the level
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// Plain is a console that prints a line per event, with its time and level,
+// skipping the events below the given level. Status changes are info
+// events, failed fetches warn events, errors error events and the rest debug
+// events.
+type Plain struct {
+	out   io.Writer
+	level Level
+	counters
+}
+
+// NewPlain creates a plain console printing on the given writer
+func NewPlain(out io.Writer, level Level) *Plain {
+	return &Plain{out: out, level: level, counters: newCounters()}
+}
+
+// log prints a line with the given level
+func (p *Plain) log(level Level, format string, args ...interface{}) {
+	if level < p.level {
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	fmt.Fprintf(p.out, "%s %-5s %s\n", time.Now().Format(time.RFC3339), level, fmt.Sprintf(format, args...))
+}
+
+// Notify prints the event, unless it is below the level of the console
+func (p *Plain) Notify(e scraper.Event) {
+	stats := p.count(e)
+
+	switch e.Type {
+	case scraper.RunFinished:
+		p.log(LevelInfo, "%s", stats.Summary())
+	case scraper.RunStarted, scraper.StatusChanged:
+		p.log(LevelInfo, "%s", describe(e))
+	case scraper.Error:
+		p.log(LevelError, "%s", describe(e))
+	case scraper.PageFetched, scraper.AssetSaved, scraper.LinkChecked:
+		if e.Err != nil || e.Status >= 400 {
+			p.log(LevelWarn, "%s", describe(e))
+		} else {
+			p.log(LevelDebug, "%s", describe(e))
+		}
+	default:
+		p.log(LevelDebug, "%s", describe(e))
+	}
+}
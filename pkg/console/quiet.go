@@ -0,0 +1,28 @@
+package console
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// Quiet is a console that only prints a summary once the run finishes
+type Quiet struct {
+	out io.Writer
+	counters
+}
+
+// NewQuiet creates a quiet console printing on the given writer
+func NewQuiet(out io.Writer) *Quiet {
+	return &Quiet{out: out, counters: newCounters()}
+}
+
+// Notify counts the event, and prints the summary once the run finishes
+func (q *Quiet) Notify(e scraper.Event) {
+	stats := q.count(e)
+
+	if e.Type == scraper.RunFinished {
+		fmt.Fprintln(q.out, stats.Summary())
+	}
+}
@@ -0,0 +1,57 @@
+package console
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// Modes a console can be selected with
+var Modes = []string{"auto", "tui", "tty", "plain", "json", "quiet"}
+
+// IsTerminal checks if the given file is a terminal
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Select creates the console of the given mode. The interactive ones print on
+// the standard output, and the others on the standard error, so they never
+// mix with a report written on the standard output. On auto mode, the TUI is
+// used only if the standard output is a terminal, and the plain console
+// otherwise.
+func Select(mode, level string) (scraper.Observer, error) {
+	if mode == "" || mode == "auto" {
+		mode = "plain"
+		if IsTerminal(os.Stdout) {
+			mode = "tui"
+		}
+	}
+
+	switch mode {
+	case "tui":
+		return NewTUI(), nil
+	case "tty":
+		return New(), nil
+	case "plain":
+		lvl := LevelInfo
+		if level != "" {
+			var err error
+			lvl, err = ParseLevel(level)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return NewPlain(os.Stderr, lvl), nil
+	case "json":
+		return NewJSON(os.Stderr), nil
+	case "quiet":
+		return NewQuiet(os.Stderr), nil
+	}
+
+	return nil, fmt.Errorf("unknown console: %s (must be one of auto, tui, tty, plain, json or quiet)", mode)
+}
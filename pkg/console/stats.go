@@ -0,0 +1,123 @@
+package console
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// Stats holds the counters of a run, shared by the consoles
+type Stats struct {
+	Domain      string    `json:"domain"`
+	Started     int       `json:"started"`
+	Scanning    int       `json:"scanning"`
+	Finished    int       `json:"finished"`
+	Attachments int       `json:"attachments"`
+	Downloaded  int       `json:"downloaded"`
+	Downloading int       `json:"downloading"`
+	Errors      int       `json:"errors"`
+	Bytes       int64     `json:"bytes"`
+	Start       time.Time `json:"start"`
+}
+
+// counters updates the stats of a run from its events, safe for concurrent
+// use
+type counters struct {
+	stats Stats
+	mutex sync.Mutex
+
+	// URLs being fetched, with the time they started
+	fetching map[string]time.Time
+}
+
+// newCounters creates the counters of a run starting now
+func newCounters() counters {
+	return counters{stats: Stats{Start: time.Now()}, fetching: make(map[string]time.Time)}
+}
+
+// count applies the given event to the stats, returning a copy of them
+func (c *counters) count(e scraper.Event) Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.apply(e)
+	return c.stats
+}
+
+// apply applies the given event to the stats. The mutex must be held.
+func (c *counters) apply(e scraper.Event) {
+	switch e.Type {
+	case scraper.RunStarted:
+		c.stats.Domain = e.URL
+	case scraper.PageQueued:
+		c.stats.Started++
+		c.stats.Scanning++
+	case scraper.FetchStarted:
+		c.fetching[e.URL] = e.Time
+	case scraper.PageFetched:
+		delete(c.fetching, e.URL)
+		c.stats.Finished++
+		c.stats.Scanning--
+		c.stats.Bytes += e.Size
+	case scraper.AssetQueued:
+		c.stats.Attachments++
+	case scraper.AssetSaved, scraper.LinkChecked:
+		delete(c.fetching, e.URL)
+		c.stats.Downloaded++
+		c.stats.Bytes += e.Size
+	case scraper.Error:
+		c.stats.Errors++
+	}
+	c.stats.Downloading = len(c.fetching)
+}
+
+// current returns a copy of the stats
+func (c *counters) current() Stats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.stats
+}
+
+// Summary returns the stats as a single line of text
+func (s Stats) Summary() string {
+	return fmt.Sprintf("Finished %s: %d pages, %d/%d attachments downloaded, %d errors, %s in %s",
+		s.Domain, s.Finished, s.Downloaded, s.Attachments, s.Errors, formatBytes(s.Bytes), time.Since(s.Start).Round(time.Millisecond))
+}
+
+// describe returns the given event as a line of text
+func describe(e scraper.Event) string {
+	var line string
+	switch e.Type {
+	case scraper.RunStarted:
+		line = "Domain: " + e.URL
+	case scraper.StatusChanged:
+		line = e.Message
+	case scraper.PageQueued, scraper.AssetQueued:
+		line = fmt.Sprintf("Queued %s (depth %d)", e.URL, e.Depth)
+		if e.Referrer != "" {
+			line += " from " + e.Referrer
+		}
+	case scraper.FetchStarted:
+		line = "Fetching " + e.URL
+	case scraper.PageFetched, scraper.AssetSaved, scraper.LinkChecked:
+		line = fmt.Sprintf("Fetched %s: %d, %s in %s", e.URL, e.Status, formatBytes(e.Size), e.Duration.Round(time.Millisecond))
+		if e.Path != "" {
+			line += ", saved on " + e.Path
+		}
+	case scraper.Redirected:
+		line = fmt.Sprintf("Redirected %s to %s (%d)", e.URL, e.Target, e.Status)
+	case scraper.Error:
+		line = e.URL
+	}
+
+	if e.Err != nil {
+		if line == "" {
+			return e.Err.Error()
+		}
+		return line + ": " + e.Err.Error()
+	}
+	return line
+}
@@ -0,0 +1,9 @@
+package console
+
+import "syscall"
+
+// Requests to get and set the attributes of a terminal
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package console
+
+import (
+	"errors"
+	"os"
+)
+
+// readKeys is not supported: keys are only read once Enter is pressed
+func readKeys(f *os.File) (restore func(), err error) {
+	return nil, errors.New("reading single keys is not supported on this system")
+}
+
+// terminalWidth returns the default width
+func terminalWidth(f *os.File) int {
+	return defaultWidth
+}
@@ -0,0 +1,50 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package console
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// ioctl sends the given request with the given argument to a file
+func ioctl(f *os.File, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// readKeys sets the terminal to read every key as soon as it is pressed,
+// without echoing it, returning a func to restore it
+func readKeys(f *os.File) (restore func(), err error) {
+	var state syscall.Termios
+	if err = ioctl(f, ioctlGetTermios, unsafe.Pointer(&state)); err != nil {
+		return
+	}
+
+	keys := state
+	keys.Lflag &^= syscall.ICANON | syscall.ECHO
+	keys.Cc[syscall.VMIN] = 1
+	keys.Cc[syscall.VTIME] = 0
+	if err = ioctl(f, ioctlSetTermios, unsafe.Pointer(&keys)); err != nil {
+		return
+	}
+
+	return func() {
+		ioctl(f, ioctlSetTermios, unsafe.Pointer(&state))
+	}, nil
+}
+
+// terminalWidth returns the number of columns of the terminal
+func terminalWidth(f *os.File) int {
+	var size struct {
+		Row, Col, X, Y uint16
+	}
+	if err := ioctl(f, syscall.TIOCGWINSZ, unsafe.Pointer(&size)); err != nil || size.Col == 0 {
+		return defaultWidth
+	}
+	return int(size.Col)
+}
@@ -0,0 +1,402 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+const (
+	// Interval between redraws
+	redrawInterval = 200 * time.Millisecond
+
+	// Window of the throughput shown
+	throughputWindow = 5 * time.Second
+
+	// Width used if the width of the terminal is unknown
+	defaultWidth = 80
+
+	// Width of the progress bar
+	barWidth = 40
+
+	// Errors kept, and errors shown at once
+	maxErrors     = 200
+	visibleErrors = 6
+)
+
+// Controller is the scraper controlled with the keys of the TUI
+type Controller interface {
+	Pause()
+	Resume()
+	Stop()
+}
+
+// sample of the counters of a run, to compute the throughput
+type sample struct {
+	time  time.Time
+	pages int
+	bytes int64
+}
+
+// TUI is an interactive console showing the URLs being fetched, the
+// throughput, the progress of the attachments and the recent errors. It is
+// redrawn at most every redrawInterval, and can pause, resume and stop the
+// scraper with the keyboard.
+type TUI struct {
+	out    io.Writer
+	status string
+
+	// Recent errors, and the first one shown
+	errors []string
+	scroll int
+
+	// Samples of the last throughputWindow
+	samples []sample
+
+	// Time the first attachment was downloaded, for the ETA
+	firstDownload time.Time
+
+	paused     bool
+	stopping   bool
+	controller Controller
+
+	// Changed since the last redraw, at lastDraw
+	dirty    bool
+	lastDraw time.Time
+
+	done    chan struct{}
+	once    sync.Once
+	restore func()
+	counters
+}
+
+// NewTUI creates a TUI, drawing on the standard output and reading the keys
+// from the standard input
+func NewTUI() *TUI {
+	t := &TUI{
+		out:      os.Stdout,
+		status:   "Starting",
+		done:     make(chan struct{}),
+		dirty:    true,
+		counters: newCounters(),
+	}
+
+	// Clear the screen and hide the cursor
+	fmt.Fprint(t.out, "\033[2J\033[?25l")
+
+	if restore, err := readKeys(os.Stdin); err == nil {
+		t.restore = restore
+	}
+
+	go t.readKeys(os.Stdin)
+	go t.loop()
+
+	return t
+}
+
+// Control sets the scraper to pause, resume and stop with the keys
+func (t *TUI) Control(c Controller) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.controller = c
+}
+
+// change applies the given change and marks the screen to be redrawn
+func (t *TUI) change(f func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	f()
+	t.dirty = true
+}
+
+// loop redraws the screen on every interval, if anything changed
+func (t *TUI) loop() {
+	ticker := time.NewTicker(redrawInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.draw(false)
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// readKeys reads the keys pressed, until the run finishes
+func (t *TUI) readKeys(in io.Reader) {
+	buf := make([]byte, 8)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			return
+		}
+
+		select {
+		case <-t.done:
+			return
+		default:
+		}
+
+		for _, key := range parseKeys(buf[:n]) {
+			t.key(key)
+		}
+	}
+}
+
+// parseKeys returns the keys of the given input, with the arrows as "up"
+// and "down"
+func parseKeys(input []byte) (keys []string) {
+	for i := 0; i < len(input); i++ {
+		if input[i] == 0x1b && i+2 < len(input) && input[i+1] == '[' {
+			switch input[i+2] {
+			case 'A':
+				keys = append(keys, "up")
+			case 'B':
+				keys = append(keys, "down")
+			}
+			i += 2
+			continue
+		}
+		keys = append(keys, strings.ToLower(string(input[i])))
+	}
+	return
+}
+
+// key handles a key pressed
+func (t *TUI) key(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	switch key {
+	case "p", " ":
+		if t.controller == nil || t.stopping {
+			return
+		}
+		if t.paused {
+			t.controller.Resume()
+		} else {
+			t.controller.Pause()
+		}
+		t.paused = !t.paused
+	case "q":
+		if t.controller == nil {
+			return
+		}
+		t.controller.Stop()
+		t.paused = false
+		t.stopping = true
+	case "up", "k":
+		t.scroll = max(t.scroll-1, 0)
+	case "down", "j":
+		t.scroll = min(t.scroll+1, max(len(t.errors)-visibleErrors, 0))
+	default:
+		return
+	}
+	t.dirty = true
+}
+
+// draw redraws the screen if anything changed, if it was not redrawn for a
+// second, to keep the clock and the throughput running, or if forced
+func (t *TUI) draw(force bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := time.Now()
+	t.samples = append(t.samples, sample{time: now, pages: t.stats.Finished, bytes: t.stats.Bytes})
+	for len(t.samples) > 1 && now.Sub(t.samples[0].time) > throughputWindow {
+		t.samples = t.samples[1:]
+	}
+
+	if !t.dirty && !force && now.Sub(t.lastDraw) < time.Second {
+		return
+	}
+	t.dirty = false
+	t.lastDraw = now
+
+	width := terminalWidth(os.Stdout)
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		line := fmt.Sprintf(format, args...)
+		if len(line) > width {
+			line = line[:max(width-2, 0)] + ".."
+		}
+		lines = append(lines, line)
+	}
+
+	state := "Running"
+	switch {
+	case t.stopping:
+		state = "Stopping"
+	case t.paused:
+		state = "Paused"
+	}
+
+	elapsed := now.Sub(t.stats.Start)
+	add("Domain: %s", t.stats.Domain)
+	add("Status: %s", t.status)
+	add("%s for %s  [p] pause/resume  [q] quit  [j/k] scroll errors", state, elapsed.Round(time.Second))
+	add("")
+
+	pages, bytes := t.throughput()
+	add("Pages: %d finished, %d scanning, %d started  (%.1f pages/s, %.2f MB/s, %s total)",
+		t.stats.Finished, t.stats.Scanning, t.stats.Started, pages, bytes/1e6, formatBytes(t.stats.Bytes))
+	add("Attachments: %s %d/%d  ETA %s", progressBar(t.stats.Downloaded, t.stats.Attachments), t.stats.Downloaded, t.stats.Attachments, t.eta(now))
+	add("")
+
+	add("Fetching (%d):", len(t.fetching))
+	urls := make([]string, 0, len(t.fetching))
+	for url := range t.fetching {
+		urls = append(urls, url)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		return t.fetching[urls[i]].Before(t.fetching[urls[j]])
+	})
+	for _, url := range urls {
+		add("  %5.1fs %s", now.Sub(t.fetching[url]).Seconds(), url)
+	}
+	add("")
+
+	add("Errors (%d):", t.stats.Errors)
+	end := min(t.scroll+visibleErrors, len(t.errors))
+	for _, err := range t.errors[t.scroll:end] {
+		add("  %s", err)
+	}
+
+	// Move to the top left corner, and clear every line before drawing it
+	var screen strings.Builder
+	screen.WriteString("\033[H")
+	for _, line := range lines {
+		screen.WriteString(line)
+		screen.WriteString("\033[K\n")
+	}
+	screen.WriteString("\033[J")
+	fmt.Fprint(t.out, screen.String())
+}
+
+// throughput returns the pages and bytes per second of the last samples
+func (t *TUI) throughput() (pages, bytes float64) {
+	if len(t.samples) < 2 {
+		return 0, 0
+	}
+
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	seconds := last.time.Sub(first.time).Seconds()
+	if seconds <= 0 {
+		return 0, 0
+	}
+
+	return float64(last.pages-first.pages) / seconds, float64(last.bytes-first.bytes) / seconds
+}
+
+// eta returns the estimated time left to download every attachment found
+func (t *TUI) eta(now time.Time) string {
+	left := t.stats.Attachments - t.stats.Downloaded
+	if t.firstDownload.IsZero() || t.stats.Downloaded == 0 || left <= 0 {
+		return "-"
+	}
+
+	perFile := now.Sub(t.firstDownload) / time.Duration(t.stats.Downloaded)
+	return (perFile * time.Duration(left)).Round(time.Second).String()
+}
+
+// progressBar returns a bar with the given progress
+func progressBar(done, total int) string {
+	filled := 0
+	if total > 0 {
+		filled = min(done*barWidth/total, barWidth)
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", barWidth-filled) + "]"
+}
+
+// formatBytes returns the given size in a human readable unit
+func formatBytes(bytes int64) string {
+	const unit = 1000
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "kMGT"[exp])
+}
+
+// reset restores the terminal and shows the cursor again
+func (t *TUI) reset() {
+	if t.restore != nil {
+		t.restore()
+	}
+	fmt.Fprint(t.out, "\033[?25h")
+}
+
+// Notify applies the event and marks the screen to be redrawn. Once the run
+// finishes, the screen is drawn a last time and the terminal restored.
+func (t *TUI) Notify(e scraper.Event) {
+	if e.Type == scraper.RunFinished {
+		t.finish()
+		return
+	}
+
+	t.change(func() {
+		t.apply(e)
+
+		switch e.Type {
+		case scraper.StatusChanged:
+			t.status = e.Message
+		case scraper.AssetSaved, scraper.LinkChecked:
+			if t.firstDownload.IsZero() {
+				t.firstDownload = e.Time
+			}
+		case scraper.Error:
+			t.addError(describe(e))
+		}
+	})
+}
+
+// addError adds an error to the recent ones. The mutex must be held.
+func (t *TUI) addError(err string) {
+	// Keep following the last errors, unless scrolled up
+	following := t.scroll >= len(t.errors)-visibleErrors
+
+	t.errors = append(t.errors, err)
+	if len(t.errors) > maxErrors {
+		t.errors = t.errors[len(t.errors)-maxErrors:]
+	}
+
+	if following {
+		t.scroll = max(len(t.errors)-visibleErrors, 0)
+	}
+	t.scroll = min(t.scroll, max(len(t.errors)-visibleErrors, 0))
+}
+
+// finish draws the screen a last time and restores the terminal
+func (t *TUI) finish() {
+	t.once.Do(func() {
+		close(t.done)
+		t.draw(true)
+		t.reset()
+
+		fmt.Fprintln(t.out, t.current().Summary())
+	})
+}
+
+// Close restores the terminal if the run didn't finish, like when the
+// scraper couldn't be created
+func (t *TUI) Close() {
+	t.once.Do(func() {
+		close(t.done)
+		t.reset()
+	})
+}
@@ -0,0 +1,100 @@
+package diff
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// Exit codes of the diff command, 0 and 1 as the ones of diff(1)
+const (
+	ExitSame    = 0
+	ExitChanged = 1
+	ExitUsage   = 2
+	ExitError   = 3
+)
+
+// Command runs the diff command with the given arguments, returning its exit code
+func Command(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	format := flags.String("format", "text", "Output format: text, json or html")
+	output := flags.String("o", "", "File to write the report to (default: standard output)")
+	ignore := flags.String("ignore", "", "Comma separated files of the download paths to leave out, like the crawl log or the broken links report, as shell patterns (optional)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ./go-download-web diff [options] <old> <new>")
+		fmt.Fprintln(flags.Output(), "Compares two download paths or manifest.json files of two runs.")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitSame
+		}
+		return ExitUsage
+	}
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	if *format != "text" && *format != "json" && *format != "html" {
+		fmt.Fprintln(os.Stderr, "invalid format: -format (must be text, json or html)")
+		return ExitUsage
+	}
+
+	report, err := compareTargets(flags.Arg(0), flags.Arg(1), scraper.SplitList(*ignore))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitError
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "text":
+		err = report.WriteText(w)
+	case "json":
+		err = report.WriteJSON(w)
+	case "html":
+		err = report.WriteHTML(w)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	if report.HasChanges() {
+		return ExitChanged
+	}
+
+	return ExitSame
+}
+
+// compareTargets loads and compares two snapshots, leaving out the files
+// matching the given patterns
+func compareTargets(beforeTarget, afterTarget string, ignore []string) (*Report, error) {
+	before, err := Load(beforeTarget, ignore...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %s", beforeTarget, err)
+	}
+
+	after, err := Load(afterTarget, ignore...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s: %s", afterTarget, err)
+	}
+
+	return Compare(before, after)
+}
@@ -0,0 +1,389 @@
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	// Manifest saved by the scraper on the download path
+	manifestFile = "manifest.json"
+
+	// Maximum number of lines compared on a page, to keep diffs cheap
+	maxLines = 5000
+)
+
+var (
+	// Files with metadata of a run, not part of the mirror, as path.Match
+	// patterns
+	metadataFiles = []string{
+		"SHA256SUMS", "manifest.json", "changes.json", "duplicates.json", "sanitized.json",
+		"_redirects", "redirects.*", ".htaccess",
+	}
+
+	// Elements whose text is not shown on a page
+	hiddenElements = []string{"script", "style", "noscript", "template"}
+)
+
+// File model, a file of a snapshot
+type File struct {
+	Path        string `json:"path"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	SHA256      string `json:"sha256"`
+}
+
+// Snapshot model, the files of a mirror run by their path
+type Snapshot struct {
+	// Download path of the run
+	Root string
+
+	Files map[string]File
+}
+
+// Line model, a line of a text diff
+type Line struct {
+	// "+" for added lines, "-" for removed ones
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Change model, a file added, removed or modified between two snapshots
+type Change struct {
+	Path string `json:"path"`
+	URL  string `json:"url,omitempty"`
+
+	// "page" or "asset"
+	Kind string `json:"kind"`
+
+	// Text diff of modified pages, with the markup ignored
+	Diff []Line `json:"diff,omitempty"`
+}
+
+// Report model, the changes between two snapshots
+type Report struct {
+	Old      string   `json:"old"`
+	New      string   `json:"new"`
+	Added    []Change `json:"added"`
+	Removed  []Change `json:"removed"`
+	Modified []Change `json:"modified"`
+}
+
+// Load loads a snapshot from a download path or a manifest file. If the
+// download path has no manifest, its files are hashed instead. The files
+// matching the given patterns, like the crawl log or the broken links report
+// of a run, are left out.
+func Load(target string, ignore ...string) (snap *Snapshot, err error) {
+	info, err := os.Stat(target)
+	if err != nil {
+		return
+	}
+
+	root := target
+	if !info.IsDir() {
+		root = filepath.Dir(target)
+		return loadManifest(root, target, ignore)
+	}
+
+	manifest := filepath.Join(root, manifestFile)
+	if _, err := os.Stat(manifest); err == nil {
+		return loadManifest(root, manifest, ignore)
+	}
+
+	return walk(root, ignore)
+}
+
+// loadManifest loads a snapshot from the manifest of a run
+func loadManifest(root, manifest string, ignore []string) (snap *Snapshot, err error) {
+	content, err := os.ReadFile(manifest)
+	if err != nil {
+		return
+	}
+
+	var files []File
+	err = json.Unmarshal(content, &files)
+	if err != nil {
+		return
+	}
+
+	snap = &Snapshot{Root: root, Files: make(map[string]File)}
+	for _, file := range files {
+		if isMetadata(file.Path, ignore) {
+			continue
+		}
+		snap.Files[file.Path] = file
+	}
+
+	return
+}
+
+// walk loads a snapshot from the files of a download path
+func walk(root string, ignore []string) (snap *Snapshot, err error) {
+	snap = &Snapshot{Root: root, Files: make(map[string]File)}
+
+	err = filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if isMetadata(rel, ignore) {
+			return nil
+		}
+
+		digest, err := hashFile(name)
+		if err != nil {
+			return err
+		}
+
+		snap.Files[rel] = File{Path: rel, SHA256: digest}
+		return nil
+	})
+
+	return
+}
+
+// isMetadata checks if the file holds metadata of a run, is a temporary file
+// or matches any of the given patterns
+func isMetadata(rel string, ignore []string) bool {
+	if strings.HasPrefix(path.Base(rel), ".tmp-") {
+		return true
+	}
+
+	for _, pattern := range append(metadataFiles, ignore...) {
+		if matched, _ := path.Match(pattern, rel); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hashFile returns the SHA-256 digest of a file
+func hashFile(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// IsPage checks if the file is an HTML page
+func (f File) IsPage() bool {
+	if strings.HasPrefix(f.ContentType, "text/html") {
+		return true
+	}
+
+	ext := strings.ToLower(path.Ext(f.Path))
+	return ext == ".html" || ext == ".htm"
+}
+
+// change creates the change of the given file
+func change(file File) Change {
+	kind := "asset"
+	if file.IsPage() {
+		kind = "page"
+	}
+
+	return Change{Path: file.Path, URL: file.URL, Kind: kind}
+}
+
+// Compare compares two snapshots, with the changes sorted by path
+func Compare(before, after *Snapshot) (report *Report, err error) {
+	report = &Report{Old: before.Root, New: after.Root}
+
+	for name, file := range after.Files {
+		previous, ok := before.Files[name]
+		if !ok {
+			report.Added = append(report.Added, change(file))
+			continue
+		}
+
+		if previous.SHA256 == file.SHA256 {
+			continue
+		}
+
+		modified := change(file)
+		if modified.Kind == "page" {
+			modified.Diff, err = diffPages(filepath.Join(before.Root, filepath.FromSlash(name)), filepath.Join(after.Root, filepath.FromSlash(name)))
+			if err != nil {
+				return
+			}
+		}
+		report.Modified = append(report.Modified, modified)
+	}
+
+	for name, file := range before.Files {
+		if _, ok := after.Files[name]; !ok {
+			report.Removed = append(report.Removed, change(file))
+		}
+	}
+
+	for _, changes := range [][]Change{report.Added, report.Removed, report.Modified} {
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Path < changes[j].Path
+		})
+	}
+
+	return
+}
+
+// HasChanges checks if there is any change on the report
+func (r *Report) HasChanges() bool {
+	return len(r.Added) > 0 || len(r.Removed) > 0 || len(r.Modified) > 0
+}
+
+// diffPages returns the text diff of two pages. Pages missing on disk, like
+// the ones of a manifest without its files, have no diff.
+func diffPages(oldFile, newFile string) ([]Line, error) {
+	oldText, err := pageText(oldFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	newText, err := pageText(newFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffLines(oldText, newText), nil
+}
+
+// pageText returns the visible text of a page, one line per text node
+func pageText(name string) (lines []string, err error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	doc, err := html.Parse(f)
+	if err != nil {
+		return
+	}
+
+	return Text(doc), nil
+}
+
+// Text returns the visible text of an HTML document, one line per text node,
+// with the whitespace collapsed
+func Text(doc *html.Node) (lines []string) {
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, hidden := range hiddenElements {
+				if n.Data == hidden {
+					return
+				}
+			}
+		}
+
+		if n.Type == html.TextNode {
+			text := strings.Join(strings.Fields(n.Data), " ")
+			if text != "" {
+				lines = append(lines, text)
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(doc)
+
+	return
+}
+
+// DiffLines returns the lines removed from a and added to b, in order, based
+// on their longest common subsequence
+func DiffLines(a, b []string) (diff []Line) {
+	// Skip the common prefix and suffix, usually most of the page
+	start := 0
+	for start < len(a) && start < len(b) && a[start] == b[start] {
+		start++
+	}
+	endA, endB := len(a), len(b)
+	for endA > start && endB > start && a[endA-1] == b[endB-1] {
+		endA--
+		endB--
+	}
+	a, b = a[start:endA], b[start:endB]
+
+	// Too big to compare line by line: everything changed
+	if len(a) > maxLines || len(b) > maxLines {
+		for _, line := range a {
+			diff = append(diff, Line{Op: "-", Text: line})
+		}
+		for _, line := range b {
+			diff = append(diff, Line{Op: "+", Text: line})
+		}
+		return
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, Line{Op: "-", Text: a[i]})
+			i++
+		default:
+			diff = append(diff, Line{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, Line{Op: "-", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, Line{Op: "+", Text: b[j]})
+	}
+
+	return
+}
@@ -0,0 +1,96 @@
+package diff_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		final := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(final), 0755))
+		assert.NoError(t, os.WriteFile(final, []byte(content), 0644))
+	}
+	return root
+}
+
+func TestCompare(t *testing.T) {
+	old := writeFiles(t, map[string]string{
+		"index.html":       "<html><body><h1>Home</h1><p>Welcome</p><script>var a = 1;</script></body></html>",
+		"about/index.html": "<html><body><p>About us</p></body></html>",
+		"logo.png":         "png",
+		"style.css":        "body {}",
+		"SHA256SUMS":       "old",
+	})
+	cur := writeFiles(t, map[string]string{
+		"index.html":         "<html><body><h1>Home</h1><p>Welcome back</p><script>var a = 2;</script></body></html>",
+		"contact/index.html": "<html><body><p>Contact</p></body></html>",
+		"logo.png":           "png",
+		"style.css":          "body { color: red; }",
+		"SHA256SUMS":         "new",
+	})
+
+	oldSnap, err := diff.Load(old)
+	assert.NoError(t, err)
+	newSnap, err := diff.Load(cur)
+	assert.NoError(t, err)
+
+	report, err := diff.Compare(oldSnap, newSnap)
+	assert.NoError(t, err)
+	assert.True(t, report.HasChanges())
+
+	assert.Equal(t, []diff.Change{{Path: "contact/index.html", Kind: "page"}}, report.Added)
+	assert.Equal(t, []diff.Change{{Path: "about/index.html", Kind: "page"}}, report.Removed)
+	assert.Equal(t, []diff.Change{
+		{Path: "index.html", Kind: "page", Diff: []diff.Line{{Op: "-", Text: "Welcome"}, {Op: "+", Text: "Welcome back"}}},
+		{Path: "style.css", Kind: "asset"},
+	}, report.Modified)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, report.WriteText(buf))
+	assert.Contains(t, buf.String(), "+ contact/index.html (page)")
+	assert.Contains(t, buf.String(), "    + Welcome back")
+
+	buf.Reset()
+	assert.NoError(t, report.WriteHTML(buf))
+	assert.Contains(t, buf.String(), `<span class="add">&#43; Welcome back</span>`)
+}
+
+func TestDiffLines(t *testing.T) {
+	assert.Nil(t, diff.DiffLines([]string{"a", "b"}, []string{"a", "b"}))
+	assert.Equal(t, []diff.Line{{Op: "+", Text: "c"}}, diff.DiffLines([]string{"a", "b"}, []string{"a", "c", "b"}))
+	assert.Equal(t, []diff.Line{{Op: "-", Text: "a"}}, diff.DiffLines([]string{"a", "b"}, []string{"b"}))
+	assert.Equal(t, []diff.Line{
+		{Op: "-", Text: "b"},
+		{Op: "+", Text: "x"},
+		{Op: "+", Text: "y"},
+	}, diff.DiffLines([]string{"a", "b", "c"}, []string{"a", "x", "y", "c"}))
+}
+
+func TestLoadMetadata(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"index.html":          "<html></html>",
+		"sanitized.json":      "{}",
+		"_redirects":          "/old /new 301",
+		"redirects.conf":      "rewrite",
+		".htaccess":           "RewriteEngine On",
+		"reports/crawl.jsonl": "{}",
+		"broken.csv":          "page,url",
+		".tmp-123":            "partial",
+	})
+
+	snap, err := diff.Load(root, "reports/*.jsonl", "broken.csv")
+	assert.NoError(t, err)
+	assert.Len(t, snap.Files, 1)
+	assert.Contains(t, snap.Files, "index.html")
+
+	snap, err = diff.Load(root)
+	assert.NoError(t, err)
+	assert.Len(t, snap.Files, 3)
+}
@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// Self-contained HTML report
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Changes between {{.Old}} and {{.New}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+.summary span { display: inline-block; margin-right: 1.5em; font-weight: bold; }
+.added { color: #22863a; }
+.removed { color: #cb2431; }
+.modified { color: #b08800; }
+ul { list-style: none; padding-left: 0; }
+li { margin: .3em 0; }
+.kind { color: #666; font-size: .85em; }
+pre { background: #f6f8fa; padding: .5em; overflow-x: auto; }
+pre .add { background: #e6ffed; display: block; }
+pre .del { background: #ffeef0; display: block; }
+</style>
+</head>
+<body>
+<h1>Changes between {{.Old}} and {{.New}}</h1>
+<p class="summary">
+<span class="added">{{len .Added}} added</span>
+<span class="removed">{{len .Removed}} removed</span>
+<span class="modified">{{len .Modified}} modified</span>
+</p>
+{{if .Added}}<h2 class="added">Added</h2>
+<ul>{{range .Added}}<li>{{.Path}} <span class="kind">{{.Kind}}</span>{{if .URL}} <a href="{{.URL}}">{{.URL}}</a>{{end}}</li>{{end}}</ul>{{end}}
+{{if .Removed}}<h2 class="removed">Removed</h2>
+<ul>{{range .Removed}}<li>{{.Path}} <span class="kind">{{.Kind}}</span>{{if .URL}} <a href="{{.URL}}">{{.URL}}</a>{{end}}</li>{{end}}</ul>{{end}}
+{{if .Modified}}<h2 class="modified">Modified</h2>
+<ul>{{range .Modified}}<li>{{if .Diff}}<details><summary>{{.Path}} <span class="kind">{{.Kind}}</span></summary>
+<pre>{{range .Diff}}<span class="{{if eq .Op "+"}}add{{else}}del{{end}}">{{.Op}} {{.Text}}</span>{{end}}</pre></details>{{else}}{{.Path}} <span class="kind">{{.Kind}}</span>{{end}}</li>{{end}}</ul>{{end}}
+</body>
+</html>
+`))
+
+// WriteText writes the report as a terminal summary
+func (r *Report) WriteText(w io.Writer) (err error) {
+	_, err = fmt.Fprintf(w, "Comparing %s with %s\nAdded: %d\nRemoved: %d\nModified: %d\n",
+		r.Old, r.New, len(r.Added), len(r.Removed), len(r.Modified))
+	if err != nil {
+		return
+	}
+
+	for _, c := range r.Added {
+		if _, err = fmt.Fprintf(w, "+ %s (%s)\n", c.Path, c.Kind); err != nil {
+			return
+		}
+	}
+
+	for _, c := range r.Removed {
+		if _, err = fmt.Fprintf(w, "- %s (%s)\n", c.Path, c.Kind); err != nil {
+			return
+		}
+	}
+
+	for _, c := range r.Modified {
+		if _, err = fmt.Fprintf(w, "~ %s (%s)\n", c.Path, c.Kind); err != nil {
+			return
+		}
+		for _, line := range c.Diff {
+			if _, err = fmt.Fprintf(w, "    %s %s\n", line.Op, line.Text); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// WriteJSON writes the report as JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteHTML writes the report as a self-contained HTML page
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
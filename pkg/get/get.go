@@ -2,15 +2,42 @@ package get
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"net/url"
 )
 
-type Get struct{}
+type Get struct {
+	client *http.Client
 
-func New() *Get {
-	return &Get{}
+	// Middlewares every request is sent through, outermost first
+	middlewares []Middleware
+}
+
+// New creates a new Get, sending every request through the given
+// middlewares. The first one is the outermost: it sees the request first,
+// and the response last.
+func New(middlewares ...Middleware) *Get {
+	return &Get{
+		middlewares: middlewares,
+		client: &http.Client{
+			Transport: Chain(http.DefaultTransport, middlewares...),
+
+			// Don't follow redirects: return them to the scraper, so it can
+			// record the whole redirect chain of every URL
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Use adds the given middlewares, inside the ones already added. It must not
+// be called while fetching.
+func (g *Get) Use(middlewares ...Middleware) {
+	g.middlewares = append(g.middlewares, middlewares...)
+	g.client.Transport = Chain(http.DefaultTransport, g.middlewares...)
 }
 
 // ParseURL parses a URL string and returns its components.
@@ -29,8 +56,25 @@ func (g *Get) ParseURL(baseURLString, relativeURLString string) (final string, e
 	return parsedURL.String(), nil
 }
 
-func (g *Get) Get(link string) (final string, status int, buff *bytes.Buffer, err error) {
-	resp, err := http.Get(link)
+// Get gets the given link, sending the given headers. If the response is a
+// redirection, final is the URL the response redirects to.
+func (g *Get) Get(link string, header http.Header) (final string, status int, buff *bytes.Buffer, respHeader http.Header, err error) {
+	return g.GetContext(context.Background(), link, header)
+}
+
+// GetContext is Get, with a context canceling the request, and the waits of
+// its middlewares
+func (g *Get) GetContext(ctx context.Context, link string, header http.Header) (final string, status int, buff *bytes.Buffer, respHeader http.Header, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := g.client.Do(req)
 	if err != nil {
 		return
 	}
@@ -43,5 +87,42 @@ func (g *Get) Get(link string) (final string, status int, buff *bytes.Buffer, er
 	}
 
 	final = resp.Request.URL.String()
-	return final, resp.StatusCode, buf, nil
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if location, lerr := resp.Location(); lerr == nil {
+			final = location.String()
+		}
+	}
+
+	return final, resp.StatusCode, buf, resp.Header, nil
+}
+
+// Head sends a HEAD request to the given link, returning its status code.
+// Servers that don't allow HEAD requests are sent a GET request instead.
+func (g *Get) Head(link string) (status int, err error) {
+	return g.HeadContext(context.Background(), link)
+}
+
+// HeadContext is Head, with a context canceling the requests
+func (g *Get) HeadContext(ctx context.Context, link string) (status int, err error) {
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, method, link, nil)
+		if err != nil {
+			return
+		}
+
+		var resp *http.Response
+		resp, err = g.client.Do(req)
+		if err != nil {
+			return
+		}
+		resp.Body.Close()
+
+		status = resp.StatusCode
+		if status != http.StatusMethodNotAllowed && status != http.StatusNotImplemented {
+			break
+		}
+	}
+
+	return
 }
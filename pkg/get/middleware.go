@@ -0,0 +1,192 @@
+package get
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Middleware wraps the round tripper sending the requests, to change the
+// requests, the responses, or how they are sent
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is a func used as a round tripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls the func with the request
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Chain wraps the given round tripper with the middlewares. The first one is
+// the outermost.
+func Chain(rt http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+
+	return rt
+}
+
+// MaxRetryDelay is the longest wait before a retry, even if the response
+// asks for a longer one
+var MaxRetryDelay = time.Minute
+
+// Retry retries the requests that failed, or got a 429 or 5xx status, up to
+// the given number of times. It waits the given backoff before the first
+// retry, doubling it before each of the next ones, unless the response has a
+// Retry-After header in seconds. No wait is longer than MaxRetryDelay.
+func Retry(retries int, backoff time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (resp *http.Response, err error) {
+			wait := backoff
+			for attempt := 0; ; attempt++ {
+				resp, err = next.RoundTrip(req)
+				if attempt >= retries || !retryable(req, resp, err) {
+					return
+				}
+
+				delay := wait
+				if resp != nil {
+					if seconds, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil && seconds >= 0 {
+						delay = time.Duration(seconds) * time.Second
+					}
+					resp.Body.Close()
+				}
+
+				if !sleep(req, min(delay, MaxRetryDelay)) {
+					return nil, req.Context().Err()
+				}
+				wait *= 2
+			}
+		})
+	}
+}
+
+// retryable checks if the request can be sent again after the given response
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	// Requests with a body can't be sent twice
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+
+	if req.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+// sleep waits for the given time, returning false if the request is canceled
+// before
+func sleep(req *http.Request, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-req.Context().Done():
+		return false
+	}
+}
+
+// RateLimit sends at most the given number of requests per second, across
+// every host. Requests over the limit wait for their turn.
+func RateLimit(perSecond float64) Middleware {
+	interval := time.Duration(float64(time.Second) / perSecond)
+
+	var mutex sync.Mutex
+	var turn time.Time
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mutex.Lock()
+			now := time.Now()
+			if turn.Before(now) {
+				turn = now
+			}
+			wait := turn.Sub(now)
+			turn = turn.Add(interval)
+			mutex.Unlock()
+
+			if wait > 0 && !sleep(req, wait) {
+				return nil, req.Context().Err()
+			}
+
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// Header sets the given header on every request to the given hosts, or to
+// any host if none is given
+func Header(key, value string, hosts ...string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !forHosts(req, hosts) {
+				return next.RoundTrip(req)
+			}
+
+			req = req.Clone(req.Context())
+			req.Header.Set(key, value)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// BasicAuth authenticates every request to the given hosts, or to any host if
+// none is given, with the given user and password
+func BasicAuth(user, password string, hosts ...string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !forHosts(req, hosts) {
+				return next.RoundTrip(req)
+			}
+
+			req = req.Clone(req.Context())
+			req.SetBasicAuth(user, password)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// BearerToken authenticates every request to the given hosts, or to any host
+// if none is given, with the given token
+func BearerToken(token string, hosts ...string) Middleware {
+	return Header("Authorization", "Bearer "+token, hosts...)
+}
+
+// MatchHost checks if the host matches the pattern, a host or a *. wildcard
+// matching its subdomains, ignoring the case
+func MatchHost(host, pattern string) bool {
+	host = strings.ToLower(host)
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+
+	return host == pattern
+}
+
+// forHosts checks if the request is to one of the given hosts, with or
+// without its port, or if there is none
+func forHosts(req *http.Request, hosts []string) bool {
+	if len(hosts) == 0 {
+		return true
+	}
+
+	for _, host := range hosts {
+		if MatchHost(req.URL.Host, host) || MatchHost(req.URL.Hostname(), host) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,192 @@
+package get_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	var order []string
+	trace := func(name string) get.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return get.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" in")
+				resp, err := next.RoundTrip(req)
+				order = append(order, name+" out")
+				return resp, err
+			})
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "server")
+	}))
+	defer server.Close()
+
+	g := get.New(trace("first"))
+	g.Use(trace("second"))
+
+	status, err := g.Head(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, []string{"first in", "second in", "server", "second out", "first out"}, order)
+}
+
+func TestRetry(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	g := get.New(get.Retry(2, time.Millisecond))
+	_, status, body, _, err := g.Get(server.URL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "ok", body.String())
+	assert.Equal(t, int32(3), requests)
+
+	// Out of retries, the last response is returned
+	atomic.StoreInt32(&requests, 0)
+	g = get.New(get.Retry(1, time.Millisecond))
+	_, status, _, _, err = g.Get(server.URL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, int32(2), requests)
+
+	// Client errors are not retried
+	atomic.StoreInt32(&requests, 0)
+	notFound := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer notFound.Close()
+
+	_, status, _, _, err = get.New(get.Retry(3, time.Millisecond)).Get(notFound.URL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, int32(1), requests)
+}
+
+func TestRetryCanceled(t *testing.T) {
+	next := get.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody, Header: http.Header{}}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", nil)
+	go cancel()
+
+	_, err := get.Retry(5, time.Hour)(next).RoundTrip(req)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRetryMaxDelay(t *testing.T) {
+	defer func(delay time.Duration) { get.MaxRetryDelay = delay }(get.MaxRetryDelay)
+	get.MaxRetryDelay = 10 * time.Millisecond
+
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	_, status, _, _, err := get.New(get.Retry(1, time.Millisecond)).Get(server.URL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestGetContextCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	// The first request is sent right away, the next one waits ten seconds
+	g := get.New(get.RateLimit(0.1))
+	ctx, cancel := context.WithCancel(context.Background())
+	_, status, _, _, err := g.GetContext(ctx, server.URL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	_, _, _, _, err = g.GetContext(ctx, server.URL, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(start), time.Second)
+
+	_, err = g.HeadContext(ctx, server.URL)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRateLimit(t *testing.T) {
+	next := get.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+	rt := get.RateLimit(100)(next)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+		_, err := rt.RoundTrip(req)
+		assert.NoError(t, err)
+	}
+
+	// The first request is sent right away, the next ones every 10ms
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestAuth(t *testing.T) {
+	var seen []string
+	next := get.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		seen = append(seen, req.Header.Get("Authorization"))
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	rt := get.Chain(next, get.BasicAuth("user", "secret", "example.com"))
+	for _, link := range []string{"https://example.com/", "https://cdn.example.org/"} {
+		req, _ := http.NewRequest(http.MethodGet, link, nil)
+		_, err := rt.RoundTrip(req)
+		assert.NoError(t, err)
+		assert.Empty(t, req.Header.Get("Authorization"), "the request of the caller is not changed")
+	}
+
+	assert.True(t, strings.HasPrefix(seen[0], "Basic "))
+	assert.Empty(t, seen[1], "credentials are only sent to the given hosts")
+
+	seen = nil
+	rt = get.Chain(next, get.BearerToken("token"))
+	req, _ := http.NewRequest(http.MethodGet, "https://other.com/", nil)
+	_, err := rt.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Bearer token"}, seen)
+}
+
+func TestMatchHost(t *testing.T) {
+	assert.True(t, get.MatchHost("example.com", "example.com"))
+	assert.True(t, get.MatchHost("Example.COM", " example.com "))
+	assert.True(t, get.MatchHost("cdn.example.com", "*.example.com"))
+	assert.True(t, get.MatchHost("a.b.example.com", "*.Example.com"))
+	assert.False(t, get.MatchHost("example.com", "*.example.com"))
+	assert.False(t, get.MatchHost("badexample.com", "*.example.com"))
+	assert.False(t, get.MatchHost("example.org", "example.com"))
+}
@@ -11,6 +11,7 @@ package get
 
 import (
 	bytes "bytes"
+	http "net/http"
 	reflect "reflect"
 
 	gomock "go.uber.org/mock/gomock"
@@ -40,20 +41,36 @@ func (m *MockHttpGet) EXPECT() *MockHttpGetMockRecorder {
 }
 
 // Get mocks base method.
-func (m *MockHttpGet) Get(arg0 string) (string, int, *bytes.Buffer, error) {
+func (m *MockHttpGet) Get(arg0 string, arg1 http.Header) (string, int, *bytes.Buffer, http.Header, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Get", arg0)
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(int)
 	ret2, _ := ret[2].(*bytes.Buffer)
-	ret3, _ := ret[3].(error)
-	return ret0, ret1, ret2, ret3
+	ret3, _ := ret[3].(http.Header)
+	ret4, _ := ret[4].(error)
+	return ret0, ret1, ret2, ret3, ret4
 }
 
 // Get indicates an expected call of Get.
-func (mr *MockHttpGetMockRecorder) Get(arg0 any) *gomock.Call {
+func (mr *MockHttpGetMockRecorder) Get(arg0, arg1 any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockHttpGet)(nil).Get), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockHttpGet)(nil).Get), arg0, arg1)
+}
+
+// Head mocks base method.
+func (m *MockHttpGet) Head(arg0 string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Head", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Head indicates an expected call of Head.
+func (mr *MockHttpGetMockRecorder) Head(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Head", reflect.TypeOf((*MockHttpGet)(nil).Head), arg0)
 }
 
 // ParseURL mocks base method.
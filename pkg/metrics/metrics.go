@@ -0,0 +1,231 @@
+// Package metrics exposes the progress of a run in the Prometheus text
+// format, to be scraped while the run proceeds.
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// Prefix of the name of every metric
+const namespace = "gdw"
+
+// Upper bounds of the fetch latency buckets, in seconds
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Classes of the fetch errors
+const (
+	ClassClientError = "client_error"
+	ClassServerError = "server_error"
+	ClassTimeout     = "timeout"
+	ClassNetwork     = "network"
+	ClassStopped     = "stopped"
+	ClassOther       = "other"
+)
+
+// histogram counts observations on cumulative buckets
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// observe adds an observation
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// Metrics is an observer counting the events of a run, served on the
+// Prometheus text format
+type Metrics struct {
+	mutex sync.Mutex
+
+	pages   uint64
+	assets  uint64
+	bytes   uint64
+	errors  map[string]uint64
+	latency histogram
+
+	// URLs being fetched, and URLs queued and not fetched yet
+	inFlight map[string]bool
+	frontier map[string]bool
+}
+
+// New creates the metrics of a run
+func New() *Metrics {
+	return &Metrics{
+		errors:   make(map[string]uint64),
+		latency:  histogram{buckets: latencyBuckets, counts: make([]uint64, len(latencyBuckets))},
+		inFlight: make(map[string]bool),
+		frontier: make(map[string]bool),
+	}
+}
+
+// Notify counts the event
+func (m *Metrics) Notify(e scraper.Event) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	switch e.Type {
+	case scraper.PageQueued, scraper.AssetQueued:
+		m.frontier[e.URL] = true
+	case scraper.FetchStarted:
+		m.inFlight[e.URL] = true
+	case scraper.PageFetched, scraper.AssetSaved, scraper.LinkChecked:
+		delete(m.frontier, e.URL)
+		delete(m.inFlight, e.URL)
+
+		if e.Type == scraper.PageFetched {
+			m.pages++
+		} else {
+			m.assets++
+		}
+		m.bytes += uint64(e.Size)
+
+		// The pages not fetched because the run was stopped took no time
+		if e.Duration > 0 {
+			m.latency.observe(e.Duration.Seconds())
+		}
+
+		if e.Err != nil {
+			m.errors[Classify(e.Status, e.Err)]++
+		}
+	}
+}
+
+// Classify returns the class of a fetch error, by the status of the response
+// or the error itself
+func Classify(status int, err error) string {
+	switch {
+	case status >= 500:
+		return ClassServerError
+	case status >= 400:
+		return ClassClientError
+	case err == nil:
+		return ClassOther
+	case errors.Is(err, scraper.ErrStopped):
+		return ClassStopped
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ClassTimeout
+		}
+		return ClassNetwork
+	}
+
+	return ClassOther
+}
+
+// ServeHTTP writes the metrics on the Prometheus text format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics on the Prometheus text format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	out := &writer{w: w}
+
+	out.metric("pages_fetched_total", "counter", "Pages fetched, including the failed ones.")
+	out.sample("pages_fetched_total", "", float64(m.pages))
+
+	out.metric("assets_fetched_total", "counter", "Attachments downloaded or checked, including the failed ones.")
+	out.sample("assets_fetched_total", "", float64(m.assets))
+
+	out.metric("fetched_bytes_total", "counter", "Bytes of the bodies fetched.")
+	out.sample("fetched_bytes_total", "", float64(m.bytes))
+
+	out.metric("fetch_errors_total", "counter", "Fetches failed, by class of error.")
+	classes := make([]string, 0, len(m.errors))
+	for class := range m.errors {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		out.sample("fetch_errors_total", fmt.Sprintf(`class="%s"`, class), float64(m.errors[class]))
+	}
+
+	out.metric("fetch_duration_seconds", "histogram", "Time to fetch a URL, following its redirects.")
+	for i, bound := range m.latency.buckets {
+		out.sample("fetch_duration_seconds_bucket", fmt.Sprintf(`le="%s"`, strconv.FormatFloat(bound, 'g', -1, 64)), float64(m.latency.counts[i]))
+	}
+	out.sample("fetch_duration_seconds_bucket", `le="+Inf"`, float64(m.latency.count))
+	out.sample("fetch_duration_seconds_sum", "", m.latency.sum)
+	out.sample("fetch_duration_seconds_count", "", float64(m.latency.count))
+
+	out.metric("in_flight_requests", "gauge", "Fetches in progress.")
+	out.sample("in_flight_requests", "", float64(len(m.inFlight)))
+
+	out.metric("frontier_size", "gauge", "Pages and attachments queued and not fetched yet.")
+	out.sample("frontier_size", "", float64(len(m.frontier)))
+
+	return out.n, out.err
+}
+
+// writer writes the lines of the metrics, keeping the first error
+type writer struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+// metric writes the help and type of a metric
+func (w *writer) metric(name, kind, help string) {
+	w.printf("# HELP %s_%s %s\n", namespace, name, help)
+	w.printf("# TYPE %s_%s %s\n", namespace, name, kind)
+}
+
+// sample writes a sample of a metric, with the given labels
+func (w *writer) sample(name, labels string, value float64) {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	w.printf("%s_%s%s %s\n", namespace, name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (w *writer) printf(format string, args ...interface{}) {
+	if w.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(w.w, format, args...)
+	w.n += int64(n)
+	w.err = err
+}
+
+// Serve serves the metrics on /metrics of the given address, like ":9090",
+// until the program exits. It fails only if the address can't be listened on.
+func Serve(addr string, m *Metrics) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m)
+
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go server.Serve(listener)
+
+	return nil
+}
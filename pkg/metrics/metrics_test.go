@@ -0,0 +1,66 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/metrics"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	m.Notify(scraper.Event{Type: scraper.PageQueued, URL: "https://example.com"})
+	m.Notify(scraper.Event{Type: scraper.PageQueued, URL: "https://example.com/about/"})
+	m.Notify(scraper.Event{Type: scraper.AssetQueued, URL: "https://example.com/logo.png"})
+	m.Notify(scraper.Event{Type: scraper.FetchStarted, URL: "https://example.com"})
+	m.Notify(scraper.Event{Type: scraper.FetchStarted, URL: "https://example.com/about/"})
+	m.Notify(scraper.Event{Type: scraper.PageFetched, URL: "https://example.com", Status: 200, Size: 100, Duration: 20 * time.Millisecond})
+	m.Notify(scraper.Event{Type: scraper.PageFetched, URL: "https://example.com/about/", Status: 404, Size: 10, Duration: 2 * time.Second, Err: errors.New("status code error: 404")})
+
+	buf := new(bytes.Buffer)
+	_, err := m.WriteTo(buf)
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE gdw_pages_fetched_total counter\ngdw_pages_fetched_total 2\n")
+	assert.Contains(t, out, "gdw_assets_fetched_total 0\n")
+	assert.Contains(t, out, "gdw_fetched_bytes_total 110\n")
+	assert.Contains(t, out, `gdw_fetch_errors_total{class="client_error"} 1`+"\n")
+	assert.Contains(t, out, `gdw_fetch_duration_seconds_bucket{le="0.01"} 0`+"\n")
+	assert.Contains(t, out, `gdw_fetch_duration_seconds_bucket{le="0.025"} 1`+"\n")
+	assert.Contains(t, out, `gdw_fetch_duration_seconds_bucket{le="2.5"} 2`+"\n")
+	assert.Contains(t, out, `gdw_fetch_duration_seconds_bucket{le="+Inf"} 2`+"\n")
+	assert.Contains(t, out, "gdw_fetch_duration_seconds_sum 2.02\n")
+	assert.Contains(t, out, "gdw_fetch_duration_seconds_count 2\n")
+	assert.Contains(t, out, "gdw_in_flight_requests 0\n")
+	assert.Contains(t, out, "gdw_frontier_size 1\n")
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassify(t *testing.T) {
+	assert.Equal(t, metrics.ClassServerError, metrics.Classify(503, errors.New("status code error: 503")))
+	assert.Equal(t, metrics.ClassClientError, metrics.Classify(404, errors.New("status code error: 404")))
+	assert.Equal(t, metrics.ClassStopped, metrics.Classify(0, scraper.ErrStopped))
+	assert.Equal(t, metrics.ClassTimeout, metrics.Classify(0, timeoutError{}))
+	assert.Equal(t, metrics.ClassOther, metrics.Classify(0, errors.New("disk full")))
+
+	_, err := http.Get("http://127.0.0.1:1/")
+	assert.Equal(t, metrics.ClassNetwork, metrics.Classify(0, err))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://127.0.0.1:1/", nil)
+	_, err = http.DefaultClient.Do(req)
+	assert.Equal(t, metrics.ClassTimeout, metrics.Classify(0, err))
+}
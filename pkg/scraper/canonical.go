@@ -0,0 +1,80 @@
+package scraper
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DuplicateGroup model, the URLs found for the same canonical URL
+type DuplicateGroup struct {
+	Canonical string   `json:"canonical"`
+	URLs      []string `json:"urls"`
+}
+
+// DuplicateGroups returns the canonical URLs found for more than one URL,
+// sorted by canonical URL
+func (s *Scraper) DuplicateGroups() (groups []DuplicateGroup) {
+	for canonical, urls := range s.Duplicates {
+		duplicated := false
+		for _, link := range urls {
+			if !s.IsURLInSlice(canonical, []string{link}) {
+				duplicated = true
+			}
+		}
+		if !duplicated {
+			continue
+		}
+
+		sorted := append([]string{}, urls...)
+		sort.Strings(sorted)
+		groups = append(groups, DuplicateGroup{Canonical: canonical, URLs: sorted})
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Canonical < groups[j].Canonical
+	})
+
+	return
+}
+
+// SaveDuplicates saves a stub page redirecting to the canonical URL on the
+// path of every duplicated URL, so the links to them work on the mirror too.
+// Pages already saved on those paths are kept.
+func (s *Scraper) SaveDuplicates() {
+	for _, group := range s.DuplicateGroups() {
+		for _, link := range group.URLs {
+			if s.IsURLInSlice(group.Canonical, []string{link}) {
+				continue
+			}
+
+			err := s.SaveRedirect(link, group.Canonical)
+			if err != nil {
+				s.fail(link, err)
+			}
+		}
+	}
+}
+
+// ExportDuplicates saves the report of duplicated pages on the download path
+func (s *Scraper) ExportDuplicates() (err error) {
+	if !s.exists(s.DownloadPath) {
+		os.MkdirAll(s.DownloadPath, 0755) // first create directory
+	}
+
+	f, err := os.Create(filepath.Join(s.DownloadPath, "duplicates.json"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	groups := s.DuplicateGroups()
+	if groups == nil {
+		groups = []DuplicateGroup{}
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(groups)
+}
@@ -0,0 +1,97 @@
+package scraper_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/console"
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTakeLinksCanonical(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	body := `<html><head><link rel="canonical" href="/article/"></head><body></body></html>`
+
+	mockHttpGet := get.NewMockHttpGet(ctrl)
+	mockHttpGet.EXPECT().Get("https://example.com/article/?utm_source=news", gomock.Any()).Return("https://example.com/article/?utm_source=news", http.StatusOK, bytes.NewBufferString(body), nil, nil)
+	mockHttpGet.EXPECT().ParseURL(gomock.Any(), gomock.Any()).DoAndReturn(get.New().ParseURL).AnyTimes()
+
+	mockObserver := console.NewMockObserver(ctrl)
+	mockObserver.EXPECT().Notify(gomock.Any()).AnyTimes()
+
+	s, err := scraper.NewFromConfig(&scraper.Config{OldDomain: "https://example.com", UseQueries: true, Simultaneous: 1}, mockHttpGet, mockObserver)
+	assert.NoError(t, err)
+
+	page, err := s.TakeLinks("https://example.com/article/?utm_source=news")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/article/", page.Canonical)
+}
+
+func TestDuplicateGroups(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com"})
+	s.Duplicates = map[string][]string{
+		"https://example.com/article/": {
+			"https://example.com/article/",
+			"https://example.com/2024/01/article/",
+			"https://example.com/article/?utm_source=news",
+		},
+		"https://example.com/about/": {
+			"https://example.com/about",
+		},
+	}
+
+	assert.Equal(t, []scraper.DuplicateGroup{
+		{
+			Canonical: "https://example.com/article/",
+			URLs: []string{
+				"https://example.com/2024/01/article/",
+				"https://example.com/article/",
+				"https://example.com/article/?utm_source=news",
+			},
+		},
+	}, s.DuplicateGroups())
+}
+
+func TestSaveCanonicalRelativeLinks(t *testing.T) {
+	getter := site(t, map[string]string{
+		"https://example.com": `<html><body><a href="/2024/01/article/">Article</a></body></html>`,
+		"https://example.com/2024/01/article/": `<html><head><link rel="canonical" href="/news/article/"></head><body>` +
+			`<img src="photo.png"><a href="../">January</a><a href="#comments">Comments</a><a href="/about/">About</a>` +
+			`</body></html>`,
+		"https://example.com/2024/01/article/photo.png": `png`,
+	})
+
+	path := t.TempDir()
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(path), scraper.WithCanonical())
+	assert.NoError(t, err)
+
+	_, err = s.Run(context.Background())
+	assert.NoError(t, err)
+
+	// The page is saved on the folder of its canonical, with its relative
+	// links resolved against the URL it was fetched from
+	saved, err := os.ReadFile(filepath.Join(path, "news", "article", "index.html"))
+	assert.NoError(t, err)
+	page := string(saved)
+	assert.Contains(t, page, `src="/2024/01/article/photo.png"`)
+	assert.Contains(t, page, `href="/2024/01/"`)
+	assert.Contains(t, page, `href="#comments"`)
+	assert.Contains(t, page, `href="/about/"`)
+
+	// The links to the duplicated URL lead to its canonical
+	home, err := os.ReadFile(filepath.Join(path, "index.html"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(home), `href="/2024/01/article/"`)
+
+	stub, err := os.ReadFile(filepath.Join(path, "2024", "01", "article", "index.html"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(stub), `url=/news/article/`)
+}
@@ -0,0 +1,373 @@
+package scraper
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Label of the links not found on any page, like the start URL
+const noReferrer = "(no referrer)"
+
+// Formats the broken links report can be written in
+var CheckFormats = []string{"text", "csv", "junit"}
+
+// LinkStatus model, the result of checking a link
+type LinkStatus struct {
+	URL    string
+	Status int
+	Error  string
+
+	// Link to another site
+	External bool
+}
+
+// Broken checks if the link could not be fetched or returned an error status
+func (l LinkStatus) Broken() bool {
+	return l.Error != "" || l.Status >= 400
+}
+
+// describe returns the status of the link as text
+func (l LinkStatus) describe() string {
+	if l.Error != "" {
+		return "error: " + l.Error
+	}
+	return fmt.Sprintf("%d %s", l.Status, http.StatusText(l.Status))
+}
+
+// PageLinks model, the links checked on a page
+type PageLinks struct {
+	Page  string
+	Links []LinkStatus
+}
+
+// checked records the status of a checked link
+func (s *Scraper) checked(link string, status int, err error, external bool) {
+	if !s.Check {
+		return
+	}
+
+	result := LinkStatus{URL: link, Status: status, External: external}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Statuses[link] = result
+}
+
+// addReferrer records that the given page links to the given URL
+func (s *Scraper) addReferrer(page, link string) {
+	if !s.Check || link == "" {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !IsInSlice(page, s.Referrers[link]) {
+		s.Referrers[link] = append(s.Referrers[link], page)
+	}
+}
+
+// addReferrers records every link, attachment and external link of a page
+func (s *Scraper) addReferrers(page Page) {
+	for _, link := range page.Links {
+		s.addReferrer(page.URL, link.Href)
+	}
+	for _, link := range page.Attachments {
+		s.addReferrer(page.URL, link)
+	}
+	for _, link := range page.External {
+		s.addReferrer(page.URL, link)
+	}
+}
+
+// externalLinks returns the links to other sites found on the given page
+func (s *Scraper) externalLinks(domain string, doc *html.Node) (links []string) {
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		// Resource hints are not links to a page
+		skip := n.Data == "link" && (HasRel(n, "preconnect", "dns-prefetch"))
+
+		if n.Type == html.ElementNode && !skip {
+			for _, a := range n.Attr {
+				if !IsInSlice(a.Key, linkAttributes) {
+					continue
+				}
+
+				link, err := s.Get.ParseURL(domain, a.Val)
+				if err != nil {
+					continue
+				}
+
+				link, _, _ = strings.Cut(link, "#")
+				if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+					continue
+				}
+
+				if !s.IsInternLink(link) && !IsInSlice(link, links) {
+					links = append(links, link)
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(doc)
+
+	return
+}
+
+// CheckAttachments checks every attachment found, and the ones found inside
+// the CSS and JS files, without saving them. The CSS and JS files are got
+// once to search them, the other attachments are sent a HEAD request.
+func (s *Scraper) CheckAttachments() {
+	for i := 0; i < len(s.Files) && !s.Stopped(); i++ {
+		link := s.Files[i]
+
+		if !strings.Contains(link, ".css") && !strings.Contains(link, ".js") {
+			s.checkLink(link, false)
+			continue
+		}
+
+		s.status("Checking " + link)
+		resp, elapsed, err := s.fetch(link, nil)
+		s.checked(link, resp.Status, err, false)
+
+		event := s.fetched(LinkChecked, link, resp, elapsed)
+		event.Err = err
+		s.emit(event)
+
+		if err != nil || resp.Status >= 400 {
+			continue
+		}
+
+		moreAttachments, err := s.attachmentsIn(link, resp)
+		if err != nil {
+			continue
+		}
+		for _, more := range moreAttachments {
+			s.addReferrer(link, more)
+			s.setOrigin(more, link, s.origin(link).Depth+1)
+			if !s.IsURLInSlice(more, s.Files) {
+				s.Files = append(s.Files, more)
+			}
+		}
+	}
+}
+
+// CheckExternalLinks sends a HEAD request to every external link found
+func (s *Scraper) CheckExternalLinks() {
+	var external []string
+	for link := range s.Referrers {
+		if _, ok := s.Statuses[link]; !ok && !s.IsInternLink(link) {
+			external = append(external, link)
+		}
+	}
+	sort.Strings(external)
+
+	for _, link := range external {
+		if s.Stopped() {
+			break
+		}
+
+		s.checkLink(link, true)
+	}
+}
+
+// checkLink sends a HEAD request to the link, or a GET one if the server
+// refuses it, and records its status
+func (s *Scraper) checkLink(link string, external bool) {
+	s.waitIfPaused()
+	s.status("Checking " + link)
+	s.emit(Event{Type: FetchStarted, URL: link})
+	start := time.Now()
+	status, err := s.head(link)
+	s.checked(link, status, err, external)
+
+	event := s.fetched(LinkChecked, link, response{Status: status}, time.Since(start))
+	event.Err = err
+	s.emit(event)
+}
+
+// CheckedLinks returns the links checked, grouped by the pages linking to
+// them, sorted by page and URL. If onlyBroken is set, only the broken links
+// are returned.
+func (s *Scraper) CheckedLinks(onlyBroken bool) (pages []PageLinks) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	byPage := make(map[string][]LinkStatus)
+	for link, status := range s.Statuses {
+		if onlyBroken && !status.Broken() {
+			continue
+		}
+
+		referrers := s.Referrers[link]
+		if len(referrers) == 0 {
+			referrers = []string{noReferrer}
+		}
+		for _, page := range referrers {
+			byPage[page] = append(byPage[page], status)
+		}
+	}
+
+	for page, links := range byPage {
+		sort.Slice(links, func(i, j int) bool {
+			return links[i].URL < links[j].URL
+		})
+		pages = append(pages, PageLinks{Page: page, Links: links})
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Page < pages[j].Page
+	})
+
+	return
+}
+
+// BrokenLinks returns the broken links, grouped by the pages linking to them
+func (s *Scraper) BrokenLinks() []PageLinks {
+	return s.CheckedLinks(true)
+}
+
+// ExportCheck writes the broken links report on the check output file, if
+// there is one
+func (s *Scraper) ExportCheck() (err error) {
+	if s.CheckOutput == "" {
+		return nil
+	}
+
+	f, err := os.Create(s.CheckOutput)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	return s.WriteCheckReport(f, s.CheckFormat)
+}
+
+// WriteCheckReport writes the broken links report in the given format
+func (s *Scraper) WriteCheckReport(w io.Writer, format string) error {
+	switch format {
+	case "", "text":
+		return s.writeCheckText(w)
+	case "csv":
+		return s.writeCheckCSV(w)
+	case "junit":
+		return s.writeCheckJUnit(w)
+	}
+
+	return fmt.Errorf("unknown check format: %s", format)
+}
+
+// writeCheckText writes the broken links of every page, and a summary
+func (s *Scraper) writeCheckText(w io.Writer) (err error) {
+	broken := 0
+	pages := s.BrokenLinks()
+	for _, page := range pages {
+		if _, err = fmt.Fprintf(w, "%s\n", page.Page); err != nil {
+			return
+		}
+		for _, link := range page.Links {
+			if _, err = fmt.Fprintf(w, "  %s  %s\n", link.URL, link.describe()); err != nil {
+				return
+			}
+			broken++
+		}
+		fmt.Fprintln(w)
+	}
+
+	_, err = fmt.Fprintf(w, "%d broken links on %d pages, %d links checked\n", broken, len(pages), len(s.Statuses))
+	return
+}
+
+// writeCheckCSV writes a row for every broken link of every page
+func (s *Scraper) writeCheckCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"page", "url", "status", "error"})
+	for _, page := range s.BrokenLinks() {
+		for _, link := range page.Links {
+			writer.Write([]string{page.Page, link.URL, strconv.Itoa(link.Status), link.Error})
+		}
+	}
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// JUnit XML report, with a test suite per page and a test case per link
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+}
+
+// writeCheckJUnit writes every link checked as a JUnit test case, failing
+// the broken ones, so CI systems can report them
+func (s *Scraper) writeCheckJUnit(w io.Writer) (err error) {
+	report := junitSuites{Name: "links"}
+	for _, page := range s.CheckedLinks(false) {
+		suite := junitSuite{Name: page.Page}
+		for _, link := range page.Links {
+			test := junitCase{Name: link.URL, Classname: page.Page}
+			if link.Broken() {
+				test.Failure = &junitFailure{Message: link.describe(), Type: "BrokenLink"}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, test)
+			suite.Tests++
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		return
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err = encoder.Encode(report); err != nil {
+		return
+	}
+
+	_, err = fmt.Fprintln(w)
+	return
+}
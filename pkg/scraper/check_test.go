@@ -0,0 +1,118 @@
+package scraper_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func checkedScraper(t *testing.T) *scraper.Scraper {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com", Check: true})
+	s.Statuses = map[string]scraper.LinkStatus{
+		"https://example.com":          {URL: "https://example.com", Status: 200},
+		"https://example.com/about/":   {URL: "https://example.com/about/", Status: 200},
+		"https://example.com/missing/": {URL: "https://example.com/missing/", Status: 404},
+		"https://example.com/logo.png": {URL: "https://example.com/logo.png", Error: "connection reset"},
+		"https://other.com/":           {URL: "https://other.com/", Status: 410, External: true},
+	}
+	s.Referrers = map[string][]string{
+		"https://example.com/about/":   {"https://example.com"},
+		"https://example.com/missing/": {"https://example.com", "https://example.com/about/"},
+		"https://example.com/logo.png": {"https://example.com/about/"},
+		"https://other.com/":           {"https://example.com/about/"},
+	}
+	return s
+}
+
+func TestBrokenLinks(t *testing.T) {
+	s := checkedScraper(t)
+
+	assert.Equal(t, []scraper.PageLinks{
+		{
+			Page: "https://example.com",
+			Links: []scraper.LinkStatus{
+				{URL: "https://example.com/missing/", Status: 404},
+			},
+		},
+		{
+			Page: "https://example.com/about/",
+			Links: []scraper.LinkStatus{
+				{URL: "https://example.com/logo.png", Error: "connection reset"},
+				{URL: "https://example.com/missing/", Status: 404},
+				{URL: "https://other.com/", Status: 410, External: true},
+			},
+		},
+	}, s.BrokenLinks())
+}
+
+func TestWriteCheckReport(t *testing.T) {
+	s := checkedScraper(t)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, s.WriteCheckReport(buf, "text"))
+	assert.Equal(t, `https://example.com
+  https://example.com/missing/  404 Not Found
+
+https://example.com/about/
+  https://example.com/logo.png  error: connection reset
+  https://example.com/missing/  404 Not Found
+  https://other.com/  410 Gone
+
+4 broken links on 2 pages, 5 links checked
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, s.WriteCheckReport(buf, "csv"))
+	assert.Equal(t, `page,url,status,error
+https://example.com,https://example.com/missing/,404,
+https://example.com/about/,https://example.com/logo.png,0,connection reset
+https://example.com/about/,https://example.com/missing/,404,
+https://example.com/about/,https://other.com/,410,
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, s.WriteCheckReport(buf, "junit"))
+	assert.Contains(t, buf.String(), `<testsuites name="links" tests="6" failures="4">`)
+	assert.Contains(t, buf.String(), `<testsuite name="(no referrer)" tests="1" failures="0">`)
+	assert.Contains(t, buf.String(), `<testcase name="https://example.com/missing/" classname="https://example.com/about/">`)
+	assert.Contains(t, buf.String(), `<failure message="404 Not Found" type="BrokenLink"></failure>`)
+
+	assert.Error(t, s.WriteCheckReport(buf, "xml"))
+}
+
+func TestCheckAttachments(t *testing.T) {
+	getter, fetched := typedSite(t, map[string][2]string{
+		"https://example.com":           {"text/html", `<html><head><link rel="stylesheet" href="/style.css"></head><body><img src="/logo.png"></body></html>`},
+		"https://example.com/style.css": {"text/css", `body { background: url(/bg.png) }`},
+	})
+
+	var headed []string
+	getter.EXPECT().Head(gomock.Any()).DoAndReturn(func(link string) (int, error) {
+		headed = append(headed, link)
+		if link == "https://example.com/bg.png" {
+			return http.StatusNotFound, nil
+		}
+		return http.StatusOK, nil
+	}).AnyTimes()
+
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithCheck(false))
+	assert.NoError(t, err)
+
+	result, err := s.Run(context.Background())
+	assert.NoError(t, err)
+
+	// The CSS file is got once, the other attachments are only sent a HEAD
+	assert.Equal(t, []string{"https://example.com/style.css"}, slices.DeleteFunc(*fetched, func(link string) bool {
+		return link == "https://example.com"
+	}))
+	assert.ElementsMatch(t, []string{"https://example.com/logo.png", "https://example.com/bg.png"}, headed)
+
+	assert.Len(t, result.Broken, 1)
+	assert.Equal(t, "https://example.com/style.css", result.Broken[0].Page)
+}
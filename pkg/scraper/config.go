@@ -0,0 +1,213 @@
+package scraper
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Prefix of the environment variables overriding the config
+const EnvPrefix = "GDW_"
+
+// Key of the profiles on a config file
+const profilesKey = "profiles"
+
+// LoadConfigFile reads a YAML or TOML config file, by its extension, and
+// applies its values to the config. The values on the top level apply to
+// every profile, and the ones of the given profile, if any, override them.
+func LoadConfigFile(name, profile string, conf *Config) error {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &values)
+	case ".toml":
+		err = toml.Unmarshal(content, &values)
+	default:
+		return fmt.Errorf("unknown config format: %s (must be .yaml, .yml or .toml)", name)
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %s", name, err)
+	}
+
+	profiles, _ := values[profilesKey].(map[string]interface{})
+	delete(values, profilesKey)
+
+	if err := applyValues(conf, values); err != nil {
+		return fmt.Errorf("error on %s: %s", name, err)
+	}
+
+	if profile == "" {
+		return nil
+	}
+
+	selected, ok := profiles[profile].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unknown profile on %s: %s", name, profile)
+	}
+
+	if err := applyValues(conf, selected); err != nil {
+		return fmt.Errorf("error on profile %s of %s: %s", profile, name, err)
+	}
+
+	return nil
+}
+
+// applyValues sets the fields of the config with the given values, by the
+// keys of their config tag
+func applyValues(conf *Config, values map[string]interface{}) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field, ok := configField(conf, "config", key)
+		if !ok {
+			return fmt.Errorf("unknown key: %s", key)
+		}
+		value := values[key]
+		if items, ok := value.([]interface{}); ok && isListKey(key) {
+			joined, err := joinList(items)
+			if err != nil {
+				return fmt.Errorf("invalid value of %s: %s", key, err)
+			}
+			value = joined
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("invalid value of %s: %s", key, err)
+		}
+	}
+
+	return nil
+}
+
+// isListKey checks if the field of the given config key is a comma separated
+// list, that can be given as a list on the config files
+func isListKey(key string) bool {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("config") == key {
+			return t.Field(i).Tag.Get("list") != ""
+		}
+	}
+	return false
+}
+
+// joinList joins the items of a list with commas
+func joinList(items []interface{}) (string, error) {
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return "", errors.New("must be a list of strings")
+		}
+		values = append(values, fmt.Sprint(item))
+	}
+	return strings.Join(values, ","), nil
+}
+
+// ApplyEnv sets the fields of the config with the environment variables
+// named after their config key, like GDW_URL or GDW_CHECK_FORMAT
+func ApplyEnv(conf *Config, lookup func(string) (string, bool)) error {
+	t := reflect.TypeOf(*conf)
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("config")
+		if key == "" {
+			continue
+		}
+
+		name := EnvVar(key)
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		if err := setField(reflect.ValueOf(conf).Elem().Field(i), value); err != nil {
+			return fmt.Errorf("invalid value of %s: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// EnvVar returns the environment variable of the given config key
+func EnvVar(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// applyFlags copies the fields of the flags set on the command line from
+// parsed to conf
+func applyFlags(flags *flag.FlagSet, parsed, conf *Config) {
+	flags.Visit(func(f *flag.Flag) {
+		from, ok := configField(parsed, "flag", f.Name)
+		if !ok {
+			return
+		}
+		to, _ := configField(conf, "flag", f.Name)
+		to.Set(from)
+	})
+}
+
+// configField returns the field of the config with the given tag value
+func configField(conf *Config, tag, name string) (reflect.Value, bool) {
+	t := reflect.TypeOf(*conf)
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get(tag) == name {
+			return reflect.ValueOf(conf).Elem().Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// setField sets a string, bool or int field from a value of a config file or
+// an environment variable
+func setField(field reflect.Value, value interface{}) error {
+	text := fmt.Sprint(value)
+
+	switch field.Kind() {
+	case reflect.String:
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return errors.New("must be a string")
+		}
+		field.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return errors.New("must be a number")
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type: %s", field.Kind())
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package scraper_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+const yamlConfig = `
+path: ./mirrors
+connections: 5
+check-format: csv
+profiles:
+  blog:
+    url: https://blog.example.com
+    path: ./mirrors/blog
+    portable: true
+  shop:
+    url: https://shop.example.com
+`
+
+const tomlConfig = `
+# Defaults of every profile
+path = "./mirrors"
+connections = 5
+check-format = 'csv'
+
+[profiles.blog]
+url = "https://blog.example.com" # The blog
+path = "./mirrors/blog"
+portable = true
+
+[profiles."shop"]
+url = "https://shop.example.com"
+`
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	for name, content := range map[string]string{
+		"gdw.yaml": yamlConfig,
+		"gdw.toml": tomlConfig,
+	} {
+		path := writeConfig(t, name, content)
+
+		conf := scraper.DefaultConfig()
+		assert.NoError(t, scraper.LoadConfigFile(path, "blog", conf), name)
+		assert.Equal(t, "https://blog.example.com", conf.OldDomain, name)
+		assert.Equal(t, "./mirrors/blog", conf.DownloadPath, name)
+		assert.Equal(t, 5, conf.Simultaneous, name)
+		assert.Equal(t, "csv", conf.CheckFormat, name)
+		assert.True(t, conf.PortablePaths, name)
+		assert.Equal(t, "jsonl", conf.CrawlLogFormat, name)
+
+		conf = scraper.DefaultConfig()
+		assert.NoError(t, scraper.LoadConfigFile(path, "shop", conf), name)
+		assert.Equal(t, "https://shop.example.com", conf.OldDomain, name)
+		assert.Equal(t, "./mirrors", conf.DownloadPath, name)
+		assert.False(t, conf.PortablePaths, name)
+
+		assert.Error(t, scraper.LoadConfigFile(path, "missing", scraper.DefaultConfig()), name)
+	}
+
+	// Unknown keys and invalid values are errors
+	assert.Error(t, scraper.LoadConfigFile(writeConfig(t, "gdw.yaml", "s: 1\n"), "", scraper.DefaultConfig()))
+	assert.Error(t, scraper.LoadConfigFile(writeConfig(t, "gdw.yaml", "connections: many\n"), "", scraper.DefaultConfig()))
+	assert.Error(t, scraper.LoadConfigFile(writeConfig(t, "gdw.toml", "path = [1, 2]\n"), "", scraper.DefaultConfig()))
+	assert.Error(t, scraper.LoadConfigFile(writeConfig(t, "gdw.ini", "path=x\n"), "", scraper.DefaultConfig()))
+}
+
+func TestLoadConfigFileLists(t *testing.T) {
+	for name, content := range map[string]string{
+		"gdw.yaml": "include:\n  - https://cdn.example.com\n  - https://static.example.com\nscope: ['*.example.com']\nstrip-params: utm_*\n",
+		"gdw.toml": "include = [\"https://cdn.example.com\", 'https://static.example.com',] # Roots\nscope = [\"*.example.com\"]\nstrip-params = \"utm_*\"\n",
+		"ml.toml":  "include = [\n  \"https://cdn.example.com\", # CDN\n  \"https://static.example.com\",\n]\nscope = [\"*.example.com\"]\nstrip-params = \"utm_*\"\n",
+	} {
+		conf := scraper.DefaultConfig()
+		assert.NoError(t, scraper.LoadConfigFile(writeConfig(t, name, content), "", conf), name)
+		assert.Equal(t, "https://cdn.example.com,https://static.example.com", conf.IncludedURLs, name)
+		assert.Equal(t, "*.example.com", conf.Scope, name)
+		assert.Equal(t, "utm_*", conf.StripParams, name)
+	}
+
+	// Only the comma separated options take lists, of strings
+	for name, content := range map[string]string{
+		"gdw.yaml": "include: [[a]]\n",
+		"gdw.toml": "include = [[\"a\"]]\n",
+		"a.yaml":   "path: [a, b]\n",
+		"a.toml":   "include = [\"a\", ]]\n",
+		"b.toml":   "include = [,]\n",
+	} {
+		assert.Error(t, scraper.LoadConfigFile(writeConfig(t, name, content), "", scraper.DefaultConfig()), content)
+	}
+}
+
+func TestLoadConfigFileFloat(t *testing.T) {
+	for name, content := range map[string]string{
+		"gdw.yaml": "rate: 0.5\n",
+		"gdw.toml": "rate = 0.5\n",
+	} {
+		conf := scraper.DefaultConfig()
+		assert.NoError(t, scraper.LoadConfigFile(writeConfig(t, name, content), "", conf), name)
+		assert.Equal(t, 0.5, conf.Rate, name)
+	}
+
+	conf := scraper.DefaultConfig()
+	assert.NoError(t, scraper.LoadConfigFile(writeConfig(t, "gdw.toml", "rate = 1_0e-1\nretries = 3\n"), "", conf))
+	assert.Equal(t, 1.0, conf.Rate)
+	assert.Equal(t, 3, conf.Retries)
+
+	assert.Error(t, scraper.LoadConfigFile(writeConfig(t, "gdw.toml", "rate = 0.5.1\n"), "", scraper.DefaultConfig()))
+	assert.Error(t, scraper.LoadConfigFile(writeConfig(t, "gdw.toml", "rate = nan\n"), "", scraper.DefaultConfig()))
+}
+
+func TestResolveConfig(t *testing.T) {
+	path := writeConfig(t, "gdw.toml", tomlConfig)
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	parsed := scraper.DefaultConfig()
+	flags.StringVar(&parsed.DownloadPath, "path", parsed.DownloadPath, "")
+	flags.IntVar(&parsed.Simultaneous, "s", parsed.Simultaneous, "")
+	flags.StringVar(&parsed.ConfigFile, "config", "", "")
+	flags.StringVar(&parsed.Profile, "profile", "", "")
+	assert.NoError(t, flags.Parse([]string{"-config", path, "-profile", "blog", "-s", "8"}))
+
+	env := map[string]string{"GDW_PATH": "./from-env", "GDW_S": "2", "GDW_CONNECTIONS": "7", "GDW_RATE": "2.5"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	conf, err := scraper.ResolveConfig(flags, parsed, lookup)
+	assert.NoError(t, err)
+
+	// Flags, then the environment, then the file, then the defaults
+	assert.Equal(t, 8, conf.Simultaneous)
+	assert.Equal(t, "./from-env", conf.DownloadPath)
+	assert.Equal(t, "https://blog.example.com", conf.OldDomain)
+	assert.Equal(t, "jsonl", conf.CrawlLogFormat)
+	assert.Equal(t, "blog", conf.Profile)
+	assert.Equal(t, 2.5, conf.Rate)
+
+	env["GDW_PORTABLE"] = "maybe"
+	_, err = scraper.ResolveConfig(flags, parsed, lookup)
+	assert.Error(t, err)
+
+	assert.Equal(t, "GDW_CHECK_FORMAT", scraper.EnvVar("check-format"))
+}
+
+func TestConfigMiddlewares(t *testing.T) {
+	conf := scraper.DefaultConfig()
+	assert.Empty(t, conf.Middlewares())
+
+	conf.OldDomain = "https://example.com"
+	conf.Retries = 2
+	conf.Rate = 10
+	conf.Auth = "user:secret"
+	conf.Token = "token"
+	assert.Len(t, conf.Middlewares(), 4)
+}
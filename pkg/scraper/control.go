@@ -0,0 +1,102 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Pause pauses the scraper: the fetches already started finish, but no new
+// one starts until the scraper is resumed
+func (s *Scraper) Pause() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.resumed == nil && !s.stopped {
+		s.resumed = make(chan struct{})
+	}
+}
+
+// Resume resumes a paused scraper
+func (s *Scraper) Resume() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.resumed != nil {
+		close(s.resumed)
+		s.resumed = nil
+	}
+}
+
+// Stop stops the scraper: no new page or attachment is fetched, and the run
+// finishes with the ones already fetched
+func (s *Scraper) Stop() {
+	s.mutex.Lock()
+	s.stopped = true
+	s.mutex.Unlock()
+
+	s.Resume()
+}
+
+// Paused checks if the scraper is paused
+func (s *Scraper) Paused() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.resumed != nil
+}
+
+// Stopped checks if the scraper was stopped
+func (s *Scraper) Stopped() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.stopped
+}
+
+// waitIfPaused blocks while the scraper is paused
+func (s *Scraper) waitIfPaused() {
+	s.mutex.Lock()
+	resumed := s.resumed
+	s.mutex.Unlock()
+
+	if resumed != nil {
+		<-resumed
+	}
+}
+
+// fetch follows the given link once the scraper is not paused, notifying
+// the fetch and its redirects, and returns how long it took
+func (s *Scraper) fetch(link string, header func(string) http.Header) (resp response, elapsed time.Duration, err error) {
+	s.waitIfPaused()
+
+	s.emit(Event{Type: FetchStarted, URL: link})
+	start := time.Now()
+	resp, err = follow(s.context(), s.Get, link, header)
+	elapsed = time.Since(start)
+
+	for _, hop := range resp.Redirects {
+		s.emit(Event{Type: Redirected, URL: hop.From, Target: hop.To, Status: hop.Status})
+	}
+
+	return
+}
+
+// context returns the context of the run, canceling its requests
+func (s *Scraper) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+
+	return s.ctx
+}
+
+// head sends a HEAD request to the link, with the context of the run if the
+// getter takes one
+func (s *Scraper) head(link string) (int, error) {
+	if g, ok := s.Get.(contextGetter); ok {
+		return g.HeadContext(s.context(), link)
+	}
+
+	return s.Get.Head(link)
+}
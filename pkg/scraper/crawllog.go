@@ -0,0 +1,195 @@
+package scraper
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Formats the crawl log can be written in
+var CrawlLogFormats = []string{"jsonl", "csv"}
+
+// Columns of the crawl log, on CSV format
+var crawlLogColumns = []string{"url", "referrer", "depth", "status", "content_type", "bytes", "duration_ms", "redirect", "path", "error"}
+
+// LogEntry model, a line of the crawl log for a single URL
+type LogEntry struct {
+	URL         string `json:"url"`
+	Referrer    string `json:"referrer"`
+	Depth       int    `json:"depth"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Bytes       int64  `json:"bytes"`
+
+	// Time to fetch the URL, following its redirects, in milliseconds
+	DurationMs float64 `json:"duration_ms"`
+
+	// Final URL, if the URL was redirected
+	Redirect string `json:"redirect"`
+
+	// Path of the file saved, relative to the download path
+	Path string `json:"path"`
+
+	Error string `json:"error"`
+}
+
+// origin model, how a URL was found
+type origin struct {
+	Referrer string
+	Depth    int
+}
+
+// CrawlLog writes a line per URL fetched, as the crawl proceeds
+type CrawlLog struct {
+	file  io.Closer
+	json  *json.Encoder
+	csv   *csv.Writer
+	mutex sync.Mutex
+
+	// First error writing the log, returned on Close
+	err error
+}
+
+// NewCrawlLog creates a crawl log writing on the given writer, in the given
+// format. The writer is closed with the log, if it is a closer.
+func NewCrawlLog(w io.Writer, format string) (*CrawlLog, error) {
+	l := &CrawlLog{}
+	if closer, ok := w.(io.Closer); ok {
+		l.file = closer
+	}
+
+	switch format {
+	case "", "jsonl":
+		l.json = json.NewEncoder(w)
+	case "csv":
+		l.csv = csv.NewWriter(w)
+		l.csv.Write(crawlLogColumns)
+		l.csv.Flush()
+	default:
+		return nil, fmt.Errorf("unknown crawl log format: %s", format)
+	}
+
+	return l, nil
+}
+
+// OpenCrawlLog creates a crawl log writing on the given file
+func OpenCrawlLog(name, format string) (*CrawlLog, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := NewCrawlLog(f, format)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Write writes an entry on the log, recording the first error to return it
+// on Close
+func (l *CrawlLog) Write(entry LogEntry) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	err := l.write(entry)
+	if err != nil && l.err == nil {
+		l.err = err
+	}
+
+	return err
+}
+
+// write writes an entry on the log, holding its lock
+func (l *CrawlLog) write(entry LogEntry) error {
+	if l.json != nil {
+		return l.json.Encode(entry)
+	}
+
+	l.csv.Write([]string{
+		entry.URL,
+		entry.Referrer,
+		strconv.Itoa(entry.Depth),
+		strconv.Itoa(entry.Status),
+		entry.ContentType,
+		strconv.FormatInt(entry.Bytes, 10),
+		strconv.FormatFloat(entry.DurationMs, 'f', 3, 64),
+		entry.Redirect,
+		entry.Path,
+		entry.Error,
+	})
+	l.csv.Flush()
+
+	return l.csv.Error()
+}
+
+// Close closes the file of the log, if any, returning the first error
+// writing it
+func (l *CrawlLog) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file != nil {
+		if err := l.file.Close(); err != nil && l.err == nil {
+			l.err = err
+		}
+	}
+	return l.err
+}
+
+// Notify writes a line for every page fetched and every attachment saved
+// or checked
+func (l *CrawlLog) Notify(e Event) {
+	if e.Type != PageFetched && e.Type != AssetSaved && e.Type != LinkChecked {
+		return
+	}
+
+	entry := LogEntry{
+		URL:         e.URL,
+		Referrer:    e.Referrer,
+		Depth:       e.Depth,
+		Status:      e.Status,
+		ContentType: e.ContentType,
+		Bytes:       e.Size,
+		DurationMs:  float64(e.Duration.Microseconds()) / 1000,
+		Redirect:    e.Target,
+		Path:        e.Path,
+	}
+	if e.Err != nil {
+		entry.Error = e.Err.Error()
+	}
+
+	l.Write(entry)
+}
+
+// setOrigin records how the given URL was found, unless it is already known
+func (s *Scraper) setOrigin(link, referrer string, depth int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.origins[link]; !ok {
+		s.origins[link] = origin{Referrer: referrer, Depth: depth}
+	}
+}
+
+// origin returns how the given URL was found. The start URL has no referrer
+// and a depth of 0.
+func (s *Scraper) origin(link string) origin {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.origins[link]
+}
+
+// relativePath returns the path of the given folder and filename, relative
+// to the download path, as it is recorded on the manifest
+func relativePath(folder, filename string) string {
+	return strings.TrimPrefix(folder+filename, "/")
+}
@@ -0,0 +1,110 @@
+package scraper_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/console"
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCrawlLog(t *testing.T) {
+	entry := scraper.LogEntry{
+		URL:         "https://example.com/about/",
+		Referrer:    "https://example.com",
+		Depth:       1,
+		Status:      200,
+		ContentType: "text/html",
+		Bytes:       1234,
+		DurationMs:  12.5,
+		Path:        "about/index.html",
+	}
+
+	buf := new(bytes.Buffer)
+	l, err := scraper.NewCrawlLog(buf, "jsonl")
+	assert.NoError(t, err)
+	assert.NoError(t, l.Write(entry))
+	assert.Equal(t, `{"url":"https://example.com/about/","referrer":"https://example.com","depth":1,"status":200,"content_type":"text/html","bytes":1234,"duration_ms":12.5,"redirect":"","path":"about/index.html","error":""}`+"\n", buf.String())
+
+	buf.Reset()
+	l, err = scraper.NewCrawlLog(buf, "csv")
+	assert.NoError(t, err)
+	assert.NoError(t, l.Write(entry))
+	assert.Equal(t, "url,referrer,depth,status,content_type,bytes,duration_ms,redirect,path,error\n"+
+		"https://example.com/about/,https://example.com,1,200,text/html,1234,12.500,,about/index.html,\n", buf.String())
+
+	_, err = scraper.NewCrawlLog(buf, "xml")
+	assert.Error(t, err)
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestCrawlLogConcurrentErrors(t *testing.T) {
+	l, err := scraper.NewCrawlLog(failingWriter{}, "jsonl")
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Notify(scraper.Event{Type: scraper.PageFetched, URL: "https://example.com/"})
+		}()
+	}
+	wg.Wait()
+
+	assert.EqualError(t, l.Close(), "disk full")
+}
+
+func TestTakeLinksLog(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	home := `<html><body><a href="/about/">About</a></body></html>`
+	about := `<html><body><a href="/gone/">Gone</a></body></html>`
+	header := http.Header{"Content-Type": []string{"text/html"}}
+
+	mockHttpGet := get.NewMockHttpGet(ctrl)
+	mockHttpGet.EXPECT().Get("https://example.com", gomock.Any()).Return("https://example.com", http.StatusOK, bytes.NewBufferString(home), header, nil)
+	mockHttpGet.EXPECT().Get("https://example.com/about/", gomock.Any()).Return("https://example.com/about/", http.StatusOK, bytes.NewBufferString(about), header, nil)
+	mockHttpGet.EXPECT().Get("https://example.com/gone/", gomock.Any()).Return("https://example.com/gone/", http.StatusNotFound, bytes.NewBufferString(""), nil, nil)
+	mockHttpGet.EXPECT().ParseURL(gomock.Any(), gomock.Any()).DoAndReturn(get.New().ParseURL).AnyTimes()
+
+	mockObserver := console.NewMockObserver(ctrl)
+	mockObserver.EXPECT().Notify(gomock.Any()).AnyTimes()
+
+	s, err := scraper.NewFromConfig(&scraper.Config{OldDomain: "https://example.com", Simultaneous: 1}, mockHttpGet, mockObserver)
+	assert.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	log, err := scraper.NewCrawlLog(buf, "csv")
+	assert.NoError(t, err)
+	s.Subscribe(log)
+
+	_, err = s.TakeLinks("https://example.com")
+	assert.NoError(t, err)
+	_, err = s.TakeLinks("https://example.com/about/")
+	assert.NoError(t, err)
+	_, err = s.TakeLinks("https://example.com/gone/")
+	assert.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[1], "https://example.com,,0,200,text/html,53,"))
+	assert.True(t, strings.HasSuffix(lines[1], ",,index.html,"))
+	assert.True(t, strings.HasPrefix(lines[2], "https://example.com/about/,https://example.com,1,200,text/html,51,"))
+	assert.True(t, strings.HasSuffix(lines[2], ",,about/index.html,"))
+	assert.True(t, strings.HasPrefix(lines[3], "https://example.com/gone/,https://example.com/about/,2,404,,0,"))
+	assert.True(t, strings.HasSuffix(lines[3], ",,,status code error: 404 on https://example.com/gone/"))
+}
@@ -2,25 +2,24 @@ package scraper
 
 import (
 	"bytes"
+	"context"
+	"net/http"
+	"sync"
 	"time"
 )
 
-// Console interface
-type Console interface {
-	AddDomain(string)
-	AddStatus(string)
-	AddStarted()
-	AddFinished()
-	AddAttachments()
-	AddDownloaded()
-	AddDownloading()
-	AddErrors(string)
-}
-
 // HttpGet interface
 type HttpGet interface {
 	ParseURL(baseURLString, relativeURLString string) (final string, err error)
-	Get(link string) (final string, status int, buff *bytes.Buffer, err error)
+	Get(link string, header http.Header) (final string, status int, buff *bytes.Buffer, respHeader http.Header, err error)
+	Head(link string) (status int, err error)
+}
+
+// contextGetter is a getter that can make its requests with a context, so
+// they are canceled with the run
+type contextGetter interface {
+	GetContext(ctx context.Context, link string, header http.Header) (final string, status int, buff *bytes.Buffer, respHeader http.Header, err error)
+	HeadContext(ctx context.Context, link string) (status int, err error)
 }
 
 type Scraper struct {
@@ -34,32 +33,32 @@ type Scraper struct {
 	// This is useful for scraping sites where content is hosted on a CDN
 	Roots []string
 
+	// Scope rules of the hosts to crawl too, each saved on its own folder
+	Scope []ScopeRule
+
+	// Host of the site, without www., saved on the root of the download path
+	siteHost string
+
 	// Path where to save the downloads
 	DownloadPath string
 
+	// Map names to ones that are safe on Windows and macOS too
+	PortablePaths bool
+
 	// Use args on URLs
 	UseQueries bool
 
-	// Number of concurrent queries
-	Simultaneous int
-
-	// Scanning now
-	Scanning chan int
-
-	// New links found
-	NewLinks chan []Links
-
-	// Pages to save
-	Pages chan Page
+	// Normalizer of the URLs found
+	Normalizer *Normalizer
 
-	// Attachments found
-	Attachments chan []string
+	// Save pages only once per canonical URL
+	UseCanonical bool
 
-	// Started
-	Started chan int
+	// Number of concurrent queries
+	Simultaneous int
 
-	// Finished
-	Finished chan int
+	// Save stub pages on the old paths of redirected pages
+	RedirectStubs bool
 
 	// Indexed pages
 	Indexed []string
@@ -73,14 +72,96 @@ type Scraper struct {
 	// Seen links
 	Seen map[string]bool
 
+	// Redirected URLs, with their full redirect chain
+	Redirects map[string][]Redirect
+
+	// Format to export the redirects found
+	RedirectsFormat string
+
+	// URLs found for each canonical URL
+	Duplicates map[string][]string
+
+	// Files saved, by their path on the download path
+	Manifest map[string]ManifestEntry
+
+	// Re-crawl with conditional requests, based on the previous run
+	Incremental bool
+
+	// Files saved on the previous run, by their URL
+	Previous map[string]ManifestEntry
+
+	// Check the links of the site instead of saving it
+	Check bool
+
+	// Check the external links too
+	CheckExternal bool
+
+	// Format and file of the broken links report
+	CheckFormat string
+	CheckOutput string
+
+	// Status of every link checked, by its URL
+	Statuses map[string]LinkStatus
+
+	// Pages linking to every URL found
+	Referrers map[string][]string
+
+	// Download the page requisites on external hosts too, on the external
+	// folder, if their host is allowed and not denied
+	External      bool
+	ExternalAllow []string
+	ExternalDeny  []string
+
+	// External assets found, with the extension of their local path if they
+	// have none
+	externals sync.Map
+
+	// Remove what makes the pages request URLs outside the roots
+	Sanitize bool
+
+	// Elements and attributes removed, and inline scripts flagged, by the
+	// sanitizer, by page URL
+	Sanitized map[string]SanitizedPage
+
+	// Transformers of the pages and files before they are saved, in order
+	Transformers []Transformer
+
+	// Log of every URL fetched
+	Log *CrawlLog
+
+	// How every URL was found, for the events
+	origins map[string]origin
+
 	// Start time
 	StartTime time.Time
 
 	// GetInterface
 	Get HttpGet
 
-	// Console
-	Con Console
+	// Observers notified of every event
+	observers []Observer
+
+	// Semaphore of the pages being fetched
+	scanning chan struct{}
+
+	// Pages being saved
+	saving sync.WaitGroup
+
+	// Stats and errors of the run, for its result
+	stats  Stats
+	errors []error
+
+	// Mutex for the fields written while saving
+	mutex sync.Mutex
+
+	// Context of the run, canceling its requests
+	ctx context.Context
+
+	// Closed once a paused scraper is resumed, nil if not paused
+	resumed chan struct{}
+
+	// Stopped before finishing
+	stopped bool
 }
 
 // Links model
@@ -94,4 +175,32 @@ type Page struct {
 	Canonical string
 	Links     []Links
 	HTML      string
+
+	// Redirect chain, if the page was redirected
+	Redirects []Redirect
+
+	// Attachments found on the page
+	Attachments []string
+
+	// Links to other sites found on the page
+	External []string
+
+	// Validators of the response, for conditional requests
+	ETag         string
+	LastModified string
+
+	// Not modified since the previous run
+	NotModified bool
+
+	// Elements and attributes removed, and inline scripts flagged, by the
+	// sanitizer
+	Removed []Removal
+	Flagged []Removal
+}
+
+// Redirect model, a single hop of a redirect chain
+type Redirect struct {
+	From   string
+	To     string
+	Status int
 }
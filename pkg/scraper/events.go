@@ -0,0 +1,154 @@
+package scraper
+
+import (
+	"errors"
+	"time"
+)
+
+// EventType is the type of an event of a run
+type EventType string
+
+// Types of the events of a run
+const (
+	// The run started on the domain on URL
+	RunStarted EventType = "run_started"
+
+	// The status of the run changed to Message
+	StatusChanged EventType = "status"
+
+	// A page was found on Referrer, Depth links away from the start URL, and
+	// queued to be fetched
+	PageQueued EventType = "page_queued"
+
+	// The fetch of a page or attachment started
+	FetchStarted EventType = "fetch_started"
+
+	// A queued page was fetched, with the given Status, Size and Duration.
+	// Err is set if it could not be fetched.
+	PageFetched EventType = "page_fetched"
+
+	// An attachment was found on Referrer, and queued to be downloaded
+	AssetQueued EventType = "asset_queued"
+
+	// An attachment was downloaded and saved on Path. Err is set if it
+	// could not be saved.
+	AssetSaved EventType = "asset_saved"
+
+	// An attachment or external link was checked, without saving it
+	LinkChecked EventType = "link_checked"
+
+	// URL redirected to Target, with the given Status
+	Redirected EventType = "redirected"
+
+	// Something went wrong, on URL if it is set
+	Error EventType = "error"
+
+	// The run finished
+	RunFinished EventType = "run_finished"
+)
+
+// ErrStopped is the error of the pages not fetched because the scraper was
+// stopped
+var ErrStopped = errors.New("stopped")
+
+// Event model, something that happened during a run
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	URL      string
+	Referrer string
+	Depth    int
+
+	Status      int
+	ContentType string
+	Size        int64
+	Duration    time.Duration
+
+	// Target of a redirection, or final URL of a redirected fetch
+	Target string
+
+	// Path of the file saved, relative to the download path
+	Path string
+
+	Message string
+	Err     error
+}
+
+// Observer is notified of every event of a run. Events are notified from
+// several goroutines, so observers must be safe for concurrent use.
+type Observer interface {
+	Notify(Event)
+}
+
+// ObserverFunc is a func used as an observer
+type ObserverFunc func(Event)
+
+// Notify calls the func with the event
+func (f ObserverFunc) Notify(e Event) {
+	f(e)
+}
+
+// Subscribe registers an observer, notified of every event from now on
+func (s *Scraper) Subscribe(o Observer) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.observers = append(s.observers, o)
+}
+
+// emit notifies every observer of the event
+func (s *Scraper) emit(e Event) {
+	s.mutex.Lock()
+	observers := s.observers
+	s.count(e)
+	s.mutex.Unlock()
+
+	notify(observers, e)
+}
+
+// notify notifies the given observers of the event
+func notify(observers []Observer, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	for _, o := range observers {
+		o.Notify(e)
+	}
+}
+
+// status notifies a change of the status of the run
+func (s *Scraper) status(message string) {
+	s.emit(Event{Type: StatusChanged, Message: message})
+}
+
+// fail notifies an error, on the given URL if it is set
+func (s *Scraper) fail(link string, err error) {
+	s.emit(Event{Type: Error, URL: link, Err: err})
+}
+
+// fetched returns the event of a fetched page or attachment, with how it was
+// found and the details of its response
+func (s *Scraper) fetched(t EventType, link string, resp response, elapsed time.Duration) Event {
+	origin := s.origin(link)
+	e := Event{
+		Type:        t,
+		URL:         link,
+		Referrer:    origin.Referrer,
+		Depth:       origin.Depth,
+		Status:      resp.Status,
+		ContentType: resp.Header.Get("Content-Type"),
+		Duration:    elapsed,
+	}
+
+	if resp.Body != nil {
+		e.Size = int64(resp.Body.Len())
+	}
+
+	if len(resp.Redirects) > 0 {
+		e.Target = resp.URL
+	}
+
+	return e
+}
@@ -0,0 +1,85 @@
+package scraper_test
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	home := `<html><body><a href="/about/">About</a></body></html>`
+	header := http.Header{"Content-Type": []string{"text/html"}}
+
+	mockHttpGet := get.NewMockHttpGet(ctrl)
+	mockHttpGet.EXPECT().Get("https://example.com", gomock.Any()).Return("https://example.com", http.StatusOK, bytes.NewBufferString(home), header, nil)
+	mockHttpGet.EXPECT().Get("https://example.com/old/", gomock.Any()).Return("https://example.com/about/", http.StatusMovedPermanently, nil, nil, nil)
+	mockHttpGet.EXPECT().Get("https://example.com/about/", gomock.Any()).Return("https://example.com/about/", http.StatusNotFound, bytes.NewBufferString(""), nil, nil)
+	mockHttpGet.EXPECT().ParseURL(gomock.Any(), gomock.Any()).DoAndReturn(get.New().ParseURL).AnyTimes()
+
+	// Record every event but the status changes
+	var mutex sync.Mutex
+	var events []scraper.Event
+	record := scraper.ObserverFunc(func(e scraper.Event) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if e.Type != scraper.StatusChanged {
+			events = append(events, e)
+		}
+	})
+
+	s, err := scraper.NewFromConfig(&scraper.Config{OldDomain: "https://example.com", Simultaneous: 1}, mockHttpGet, record)
+	assert.NoError(t, err)
+
+	// Every subscriber is notified of every event
+	var count int
+	s.Subscribe(scraper.ObserverFunc(func(e scraper.Event) {
+		if e.Type != scraper.StatusChanged {
+			count++
+		}
+	}))
+
+	events = nil
+	_, err = s.TakeLinks("https://example.com")
+	assert.NoError(t, err)
+	_, err = s.TakeLinks("https://example.com/old/")
+	assert.Error(t, err)
+
+	var types []scraper.EventType
+	for _, e := range events {
+		assert.False(t, e.Time.IsZero())
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []scraper.EventType{
+		scraper.PageQueued, scraper.FetchStarted, scraper.PageFetched,
+		scraper.PageQueued, scraper.FetchStarted, scraper.Redirected, scraper.PageFetched, scraper.Error,
+	}, types)
+	assert.Equal(t, len(events), count)
+
+	fetched := events[2]
+	assert.Equal(t, "https://example.com", fetched.URL)
+	assert.Equal(t, http.StatusOK, fetched.Status)
+	assert.Equal(t, "text/html", fetched.ContentType)
+	assert.Equal(t, int64(len(home)), fetched.Size)
+	assert.Equal(t, "index.html", fetched.Path)
+	assert.NoError(t, fetched.Err)
+
+	redirected := events[5]
+	assert.Equal(t, "https://example.com/old/", redirected.URL)
+	assert.Equal(t, "https://example.com/about/", redirected.Target)
+	assert.Equal(t, http.StatusMovedPermanently, redirected.Status)
+
+	failed := events[6]
+	assert.Equal(t, "https://example.com/old/", failed.URL)
+	assert.Equal(t, "https://example.com/about/", failed.Target)
+	assert.Equal(t, http.StatusNotFound, failed.Status)
+	assert.Error(t, failed.Err)
+	assert.Equal(t, "https://example.com/old/", events[7].URL)
+}
@@ -0,0 +1,148 @@
+package scraper
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"golang.org/x/net/html"
+)
+
+// Folder of the download path where the assets of external hosts are saved
+const ExternalFolder = "_external"
+
+// Link types of the links to page requisites, like stylesheets and icons
+var requisiteRels = []string{"stylesheet", "icon", "shortcut", "apple-touch-icon", "mask-icon", "manifest", "preload", "modulepreload"}
+
+// externalAsset checks if the link is an asset on an external host that can
+// be downloaded, returning it without its fragment
+func (s *Scraper) externalAsset(link string) (string, bool) {
+	if !s.External || s.IsInternLink(link) {
+		return "", false
+	}
+
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+
+	if !s.HostAllowed(u.Hostname()) {
+		return "", false
+	}
+
+	u.Fragment = ""
+	return u.String(), true
+}
+
+// HostAllowed checks if the assets of the given external host can be
+// downloaded: it must be on the allow list, if there is one, and not on the
+// deny list. A *. prefix matches any subdomain.
+func (s *Scraper) HostAllowed(host string) bool {
+	host = strings.ToLower(host)
+
+	for _, pattern := range s.ExternalDeny {
+		if get.MatchHost(host, pattern) {
+			return false
+		}
+	}
+
+	if len(s.ExternalAllow) == 0 {
+		return true
+	}
+
+	for _, pattern := range s.ExternalAllow {
+		if get.MatchHost(host, pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addExternal records the link as an external asset to download, if it is
+// one, returning it. Links without extension get the given one on their
+// local path.
+func (s *Scraper) addExternal(link, ext string) (string, bool) {
+	asset, ok := s.externalAsset(link)
+	if !ok {
+		return "", false
+	}
+
+	s.externals.LoadOrStore(asset, ext)
+	return asset, true
+}
+
+// isExternal checks if the link was recorded as an external asset
+func (s *Scraper) isExternal(link string) bool {
+	_, ok := s.externals.Load(link)
+	return ok
+}
+
+// externalPath returns the local path of an external asset, without its
+// query, on the external folder
+func (s *Scraper) externalPath(link string) (string, bool) {
+	ext, ok := s.externals.Load(link)
+	if !ok {
+		return "", false
+	}
+
+	link, _, _ = strings.Cut(link, "?")
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", false
+	}
+
+	local := "/" + ExternalFolder + "/" + u.Host + u.EscapedPath()
+	if strings.HasSuffix(local, "/") {
+		local += "index"
+	}
+	if path.Ext(local) == "" {
+		local += ext.(string)
+	}
+
+	return local, true
+}
+
+// requisiteExt returns the extension of the files linked by the node, if it
+// is a stylesheet or a script
+func requisiteExt(n *html.Node) string {
+	switch {
+	case n.Data == "link" && HasRel(n, "stylesheet"):
+		return ".css"
+	case n.Data == "script":
+		return ".js"
+	}
+
+	return ""
+}
+
+// isRequisiteLink checks if the link node links to a page requisite
+func isRequisiteLink(n *html.Node) bool {
+	for _, rel := range requisiteRels {
+		if HasRel(n, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// rewriteCSS rewrites the url() of the external assets on the given CSS to
+// their local paths
+func (s *Scraper) rewriteCSS(base, css string) string {
+	return urlsInCSS.ReplaceAllStringFunc(css, func(match string) string {
+		sub := urlsInCSS.FindStringSubmatch(match)
+		link, err := s.Get.ParseURL(base, strings.TrimSpace(sub[1]))
+		if err != nil {
+			return match
+		}
+
+		asset, ok := s.externalAsset(link)
+		if !ok || !s.isExternal(asset) {
+			return match
+		}
+
+		return "url(" + s.LocalURL(asset) + ")"
+	})
+}
@@ -0,0 +1,123 @@
+package scraper_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const withCDN = `<html><head>` +
+	`<link rel="stylesheet" href="https://fonts.googleapis.com/css2?family=Roboto">` +
+	`<link rel="stylesheet" href="/style.css">` +
+	`<script src="https://cdn.jsdelivr.net/npm/app@1/app.min.js"></script>` +
+	`</head><body>` +
+	`<img src="https://images.tracker.com/banner.png">` +
+	`<a href="https://cdn.jsdelivr.net/npm/app@1/README.pdf">Docs</a>` +
+	`<div style="background: url('https://cdn.jsdelivr.net/bg.jpg')"></div>` +
+	`</body></html>`
+
+// typedSite returns a getter serving the given files with their content
+// types, with a 404 for any other URL
+func typedSite(t *testing.T, files map[string][2]string) (*get.MockHttpGet, *[]string) {
+	ctrl := gomock.NewController(t)
+
+	var fetched []string
+	mockHttpGet := get.NewMockHttpGet(ctrl)
+	mockHttpGet.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(link string, header http.Header) (string, int, *bytes.Buffer, http.Header, error) {
+		fetched = append(fetched, link)
+		file, ok := files[link]
+		if !ok {
+			return link, http.StatusNotFound, bytes.NewBufferString(""), nil, nil
+		}
+		return link, http.StatusOK, bytes.NewBufferString(file[1]), http.Header{"Content-Type": []string{file[0]}}, nil
+	}).AnyTimes()
+	mockHttpGet.EXPECT().ParseURL(gomock.Any(), gomock.Any()).DoAndReturn(get.New().ParseURL).AnyTimes()
+
+	return mockHttpGet, &fetched
+}
+
+func TestExternal(t *testing.T) {
+	getter, fetched := typedSite(t, map[string][2]string{
+		"https://example.com":                             {"text/html", withCDN},
+		"https://example.com/style.css":                   {"text/css", `@font-face { src: url(https://fonts.gstatic.com/s/roboto.woff2) }`},
+		"https://fonts.googleapis.com/css2?family=Roboto": {"text/css; charset=utf-8", `@font-face { src: url(https://fonts.gstatic.com/s/roboto.woff2) format('woff2') }`},
+		"https://fonts.gstatic.com/s/roboto.woff2":        {"font/woff2", "woff2"},
+		"https://cdn.jsdelivr.net/npm/app@1/app.min.js":   {"application/javascript", "app()"},
+		"https://cdn.jsdelivr.net/bg.jpg":                 {"image/jpeg", "jpg"},
+		"https://images.tracker.com/banner.png":           {"image/png", "png"},
+		"https://cdn.jsdelivr.net/npm/app@1/README.pdf":   {"application/pdf", "pdf"},
+	})
+
+	path := t.TempDir()
+	s, err := scraper.New("https://example.com",
+		scraper.WithGetter(getter),
+		scraper.WithDownloadPath(path),
+		scraper.WithExternal("fonts.googleapis.com", "*.gstatic.com", "*.jsdelivr.net"),
+	)
+	assert.NoError(t, err)
+
+	result, err := s.Run(context.Background())
+	assert.NoError(t, err)
+
+	files := append([]string{}, result.Files...)
+	sort.Strings(files)
+	assert.Equal(t, []string{
+		"https://cdn.jsdelivr.net/bg.jpg",
+		"https://cdn.jsdelivr.net/npm/app@1/app.min.js",
+		"https://example.com/style.css",
+		"https://fonts.googleapis.com/css2?family=Roboto",
+		"https://fonts.gstatic.com/s/roboto.woff2",
+	}, files)
+
+	// Hosts not allowed and links that are not page requisites are not fetched
+	assert.NotContains(t, *fetched, "https://images.tracker.com/banner.png")
+	assert.NotContains(t, *fetched, "https://cdn.jsdelivr.net/npm/app@1/README.pdf")
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(path, filepath.FromSlash(name)))
+		assert.NoError(t, err, name)
+		return string(data)
+	}
+
+	_, fonts := s.PreparePathsFile("https://fonts.googleapis.com/css2?family=Roboto")
+	assert.Regexp(t, `^css2-[0-9a-f]{8}\.css$`, fonts)
+	assert.Equal(t, "@font-face { src: url(/_external/fonts.gstatic.com/s/roboto.woff2) format('woff2') }", read("_external/fonts.googleapis.com/"+fonts))
+	assert.Equal(t, "@font-face { src: url(/_external/fonts.gstatic.com/s/roboto.woff2) }", read("style.css"))
+	assert.Equal(t, "woff2", read("_external/fonts.gstatic.com/s/roboto.woff2"))
+	assert.Equal(t, "app()", read("_external/cdn.jsdelivr.net/npm/app@1/app.min.js"))
+	assert.Equal(t, "jpg", read("_external/cdn.jsdelivr.net/bg.jpg"))
+
+	page := read("index.html")
+	assert.Contains(t, page, `href="/_external/fonts.googleapis.com/`+fonts+`"`)
+	assert.Contains(t, page, `src="/_external/cdn.jsdelivr.net/npm/app@1/app.min.js"`)
+	assert.Contains(t, page, `url(/_external/cdn.jsdelivr.net/bg.jpg)`)
+	assert.Contains(t, page, `src="https://images.tracker.com/banner.png"`)
+	assert.Contains(t, page, `href="https://cdn.jsdelivr.net/npm/app@1/README.pdf"`)
+}
+
+func TestHostAllowed(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com"})
+	assert.True(t, s.HostAllowed("cdn.example.org"))
+
+	s.ExternalAllow = []string{"fonts.googleapis.com", "*.jsdelivr.net"}
+	s.ExternalDeny = []string{"evil.jsdelivr.net"}
+	assert.True(t, s.HostAllowed("fonts.googleapis.com"))
+	assert.True(t, s.HostAllowed("CDN.jsdelivr.net"))
+	assert.False(t, s.HostAllowed("jsdelivr.net"))
+	assert.False(t, s.HostAllowed("evil.jsdelivr.net"))
+	assert.False(t, s.HostAllowed("unpkg.com"))
+
+	s.ExternalAllow = nil
+	assert.True(t, s.HostAllowed("unpkg.com"))
+	assert.False(t, s.HostAllowed(strings.ToUpper("evil.jsdelivr.net")))
+}
@@ -1,10 +1,13 @@
 package scraper
 
 import (
+	"net/http"
 	"net/url"
 	"os"
 	"regexp"
 	"strings"
+
+	"golang.org/x/net/html"
 )
 
 var (
@@ -43,8 +46,14 @@ var (
 	validJSRequire = regexp.MustCompile(`require\s*\(\s*['"](.*?)['"]\s*\)`)
 )
 
-// IsInternLink checks if a link is intern
+// IsInternLink checks if a link is intern: under one of the roots, or in
+// the scope
 func (s *Scraper) IsInternLink(link string) bool {
+	return s.underRoot(link) || s.InScope(link)
+}
+
+// underRoot checks if a link starts with one of the roots
+func (s *Scraper) underRoot(link string) bool {
 	for _, root := range s.Roots {
 		if strings.Index(link, root) == 0 {
 			return true
@@ -79,22 +88,34 @@ func (s *Scraper) SanitizeURL(link string) string {
 		tram = strings.Split(tram, "?")[0]
 	}
 
+	if s.Normalizer != nil {
+		tram = s.Normalizer.Normalize(tram)
+	}
+
 	// avoid index out of range
 	if len(tram) == 0 {
 		return tram
 	}
 
+	// The trailing slash goes on the path, before the query
+	tram, query, _ := strings.Cut(tram, "?")
+
 	if !s.IsValidExtension(tram) && !s.HasRenderedExtension(tram) {
 		if string(tram[len(tram)-1]) != "/" {
 			tram = tram + "/"
 		}
 	}
 
+	if query != "" {
+		tram = tram + "?" + query
+	}
+
 	return tram
 }
 
 // IsValidExtension check if an extension is valid
 func (s *Scraper) IsValidExtension(link string) bool {
+	link, _, _ = strings.Cut(link, "?")
 	if !strings.Contains(link, ".") {
 		return false
 	}
@@ -115,6 +136,7 @@ func (s *Scraper) IsValidExtension(link string) bool {
 
 // HasRenderedExtension checks if the link has a rendered extension
 func (s *Scraper) HasRenderedExtension(link string) bool {
+	link, _, _ = strings.Cut(link, "?")
 	if !strings.Contains(link, ".") {
 		return false
 	}
@@ -175,6 +197,7 @@ func (s *Scraper) IsValidAttachment(link string) bool {
 		return false
 	}
 
+	link, _, _ = strings.Cut(link, "?")
 	return s.IsValidExtension(s.RemoveTrailingSlash(link))
 }
 
@@ -246,12 +269,27 @@ func (s *Scraper) GetInsideAttachments(link string) (attachments []string, err e
 		link = RemoveLastSlash(link)
 	}
 
-	got, _, buf, err := s.Get.Get(link)
+	resp, _, err := s.fetch(link, s.conditionalHeader)
 	if err != nil {
 		return
 	}
 
-	body := buf.String()
+	return s.attachmentsIn(link, resp)
+}
+
+// attachmentsIn returns the attachments found on the given response to the
+// given CSS or JS file
+func (s *Scraper) attachmentsIn(link string, resp response) (attachments []string, err error) {
+	got := resp.URL
+	body := resp.Body.String()
+
+	// Not modified since the previous run: read the saved file
+	if resp.Status == http.StatusNotModified {
+		body, err = s.previousContent(link)
+		if err != nil {
+			return
+		}
+	}
 
 	// First, search for JavaScript
 	if strings.Contains(got, ".js") {
@@ -262,6 +300,10 @@ func (s *Scraper) GetInsideAttachments(link string) (attachments []string, err e
 			if found != "" {
 				link, err := s.Get.ParseURL(got, found)
 				if err == nil {
+					if asset, ok := s.addExternal(link, ".js"); ok {
+						attachments = append(attachments, asset)
+						continue
+					}
 					foundLink := s.SanitizeURL(link)
 					if s.IsValidAttachment(foundLink) {
 						attachments = append(attachments, foundLink)
@@ -272,7 +314,7 @@ func (s *Scraper) GetInsideAttachments(link string) (attachments []string, err e
 	}
 
 	// Second, search for CSS
-	if strings.Contains(got, ".css") {
+	if s.isCSS(got, resp.Header.Get("Content-Type")) {
 		matches := urlsInCSS.FindAllStringSubmatch(body, -1)
 		for _, match := range matches {
 			if len(match) < 2 {
@@ -288,6 +330,11 @@ func (s *Scraper) GetInsideAttachments(link string) (attachments []string, err e
 				continue
 			}
 
+			if asset, ok := s.addExternal(found, ""); ok {
+				attachments = append(attachments, asset)
+				continue
+			}
+
 			foundLink := s.SanitizeURL(found)
 			if s.IsValidAttachment(foundLink) {
 				attachments = append(attachments, foundLink)
@@ -298,6 +345,16 @@ func (s *Scraper) GetInsideAttachments(link string) (attachments []string, err e
 	return
 }
 
+// isCSS checks if the file on the given URL, with the given content type, is
+// a stylesheet. External assets linked as stylesheets are too.
+func (s *Scraper) isCSS(link, contentType string) bool {
+	if ext, ok := s.externals.Load(link); ok && ext == ".css" {
+		return true
+	}
+
+	return strings.Contains(link, ".css") || strings.HasPrefix(strings.ToLower(contentType), "text/css")
+}
+
 // getJSURLEmbedded from JavaScript
 func (s *Scraper) getJSURLEmbedded(body string) (url string) {
 	// Use a regular expression to find import statements or require functions
@@ -323,6 +380,71 @@ func (s *Scraper) getJSURLEmbedded(body string) (url string) {
 	return url
 }
 
+// isMetaRefresh checks if the node is a <meta http-equiv="refresh"> tag
+func isMetaRefresh(n *html.Node) bool {
+	for _, a := range n.Attr {
+		if a.Key == "http-equiv" && strings.EqualFold(strings.TrimSpace(a.Val), "refresh") {
+			return true
+		}
+	}
+	return false
+}
+
+// HasRel checks if the rel attribute of the node contains any of the given
+// link types
+func HasRel(n *html.Node, rels ...string) bool {
+	for _, a := range n.Attr {
+		if a.Key != "rel" {
+			continue
+		}
+		for _, val := range strings.Fields(a.Val) {
+			for _, rel := range rels {
+				if strings.EqualFold(val, rel) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Attr returns the value of the given attribute of the node
+func Attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+
+	return ""
+}
+
+// ParseMetaRefresh returns the URL of the content of a meta refresh tag,
+// like "5; url=https://example.com/", or an empty string if there is none
+func ParseMetaRefresh(content string) string {
+	_, target, found := strings.Cut(content, ";")
+	if !found {
+		// Some sites use a comma instead of a semicolon
+		_, target, found = strings.Cut(content, ",")
+		if !found {
+			return ""
+		}
+	}
+
+	target = strings.TrimSpace(target)
+	if len(target) < 3 || !strings.EqualFold(target[:3], "url") {
+		return ""
+	}
+
+	target = strings.TrimSpace(target[3:])
+	if len(target) == 0 || target[0] != '=' {
+		return ""
+	}
+
+	target = strings.TrimSpace(target[1:])
+	return strings.Trim(target, `'"`)
+}
+
 // GetPath returns the path of a given URL
 func (s *Scraper) GetPath(url string) (path string) {
 	paths := strings.Split(url, "/")
@@ -358,7 +480,8 @@ func (s *Scraper) GetLastFolder(path string) string {
 	return paths[len(paths)-2]
 }
 
-// RemoveDomain returns only the path, without domain, from the given link
+// RemoveDomain returns only the path, without domain, from the given link.
+// The links in the scope keep the folder of their host.
 func (s *Scraper) RemoveDomain(link string) string {
 	for _, root := range s.Roots {
 		if strings.Index(link, root) == 0 {
@@ -366,6 +489,14 @@ func (s *Scraper) RemoveDomain(link string) string {
 		}
 	}
 
+	if host, rest, ok := splitLink(link); ok && s.InScope(link) {
+		folder := s.hostFolder(host)
+		if folder != "" && !strings.HasPrefix(rest, "/") {
+			rest = "/" + rest
+		}
+		return folder + rest
+	}
+
 	return link
 }
 
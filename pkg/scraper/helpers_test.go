@@ -18,20 +18,12 @@ func initiate(t *testing.T, conf *scraper.Config) *scraper.Scraper {
 	status := http.StatusOK
 	final := conf.OldDomain
 
-	mockConsole := console.NewMockConsole(ctrl)
-	mockConsole.EXPECT().AddStatus(gomock.Any()).AnyTimes()
-	mockConsole.EXPECT().AddDomain(gomock.Any()).AnyTimes()
+	mockObserver := console.NewMockObserver(ctrl)
+	mockObserver.EXPECT().Notify(gomock.Any()).AnyTimes()
 
-	mockHttpGet.EXPECT().Get(conf.OldDomain).Return(final, status, nil, nil).AnyTimes()
+	mockHttpGet.EXPECT().Get(conf.OldDomain, gomock.Any()).Return(final, status, nil, nil, nil).AnyTimes()
 
-	mockConsole.EXPECT().AddDownloaded().AnyTimes()
-	mockConsole.EXPECT().AddDownloading().AnyTimes()
-	mockConsole.EXPECT().AddStarted().AnyTimes()
-	mockConsole.EXPECT().AddFinished().AnyTimes()
-	mockConsole.EXPECT().AddErrors(gomock.Any()).AnyTimes()
-	mockConsole.EXPECT().AddAttachments().AnyTimes()
-
-	s, err := scraper.New(conf, mockHttpGet, mockConsole)
+	s, err := scraper.NewFromConfig(conf, mockHttpGet, mockObserver)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -471,3 +463,17 @@ func TestIsLinkScanned(t *testing.T) {
 		assert.False(t, s.IsLinkScanned(link, links))
 	}
 }
+
+func TestParseMetaRefresh(t *testing.T) {
+	// Test refresh with URL
+	assert.Equal(t, "https://example.com/new/", scraper.ParseMetaRefresh("0; url=https://example.com/new/"))
+	assert.Equal(t, "/new/", scraper.ParseMetaRefresh("5;URL=/new/"))
+	assert.Equal(t, "/new/", scraper.ParseMetaRefresh("0; URL='/new/'"))
+	assert.Equal(t, "/new/", scraper.ParseMetaRefresh(`0; url="/new/"`))
+	assert.Equal(t, "/new/", scraper.ParseMetaRefresh("0, url = /new/"))
+
+	// Test refresh without URL
+	assert.Equal(t, "", scraper.ParseMetaRefresh("30"))
+	assert.Equal(t, "", scraper.ParseMetaRefresh("0;"))
+	assert.Equal(t, "", scraper.ParseMetaRefresh("0; /new/"))
+}
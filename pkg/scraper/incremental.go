@@ -0,0 +1,168 @@
+package scraper
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Report of the changes since the previous run, saved on the download path
+const changesFile = "changes.json"
+
+// Changes model, the URLs that changed since the previous run
+type Changes struct {
+	New       []string `json:"new"`
+	Changed   []string `json:"changed"`
+	Unchanged []string `json:"unchanged"`
+	Removed   []string `json:"removed"`
+}
+
+// LoadManifest loads the manifest saved on the given download path, by URL.
+// It is empty if there is no manifest yet.
+func LoadManifest(downloadPath string) (entries map[string]ManifestEntry, err error) {
+	entries = make(map[string]ManifestEntry)
+
+	content, err := os.ReadFile(filepath.Join(downloadPath, manifestFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return
+	}
+
+	var list []ManifestEntry
+	err = json.Unmarshal(content, &list)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range list {
+		entries[entry.URL] = entry
+	}
+
+	return
+}
+
+// previousFile returns the entry of the given URL on the previous run, if its
+// file is still on the download path
+func (s *Scraper) previousFile(link string) (entry ManifestEntry, ok bool) {
+	entry, ok = s.Previous[link]
+	if !ok {
+		return
+	}
+
+	ok = s.exists(filepath.Join(s.DownloadPath, filepath.FromSlash(entry.Path)))
+	return
+}
+
+// conditionalHeader returns the headers to only get the given URL if it was
+// modified since the previous run
+func (s *Scraper) conditionalHeader(link string) http.Header {
+	if !s.Incremental {
+		return nil
+	}
+
+	entry, ok := s.previousFile(link)
+	if !ok {
+		return nil
+	}
+
+	header := http.Header{}
+	if entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
+
+	return header
+}
+
+// keepFile keeps the file of the given URL saved on the previous run,
+// recording it on the manifest of this run
+func (s *Scraper) keepFile(link string) bool {
+	entry, ok := s.previousFile(link)
+	if !ok {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Manifest[entry.Path] = entry
+	return true
+}
+
+// previousContent returns the content of the file of the given URL saved on
+// the previous run
+func (s *Scraper) previousContent(link string) (string, error) {
+	entry, ok := s.previousFile(link)
+	if !ok {
+		return "", fmt.Errorf("no previous file for %s", link)
+	}
+
+	content, err := os.ReadFile(filepath.Join(s.DownloadPath, filepath.FromSlash(entry.Path)))
+	return string(content), err
+}
+
+// Changes returns the URLs that are new, changed, unchanged or removed since
+// the previous run, each of them sorted
+func (s *Scraper) Changes() (changes Changes) {
+	current := make(map[string]ManifestEntry)
+	for _, entry := range s.ManifestEntries() {
+		current[entry.URL] = entry
+	}
+
+	for link, entry := range current {
+		previous, ok := s.Previous[link]
+		switch {
+		case !ok:
+			changes.New = append(changes.New, link)
+		case previous.SHA256 != entry.SHA256:
+			changes.Changed = append(changes.Changed, link)
+		default:
+			changes.Unchanged = append(changes.Unchanged, link)
+		}
+	}
+
+	for link := range s.Previous {
+		if _, ok := current[link]; !ok {
+			changes.Removed = append(changes.Removed, link)
+		}
+	}
+
+	sort.Strings(changes.New)
+	sort.Strings(changes.Changed)
+	sort.Strings(changes.Unchanged)
+	sort.Strings(changes.Removed)
+
+	return
+}
+
+// SaveChanges saves the report of the changes since the previous run on the
+// download path, showing the summary on the console
+func (s *Scraper) SaveChanges() (err error) {
+	changes := s.Changes()
+
+	s.status(fmt.Sprintf("%d new, %d changed, %d unchanged, %d removed",
+		len(changes.New), len(changes.Changed), len(changes.Unchanged), len(changes.Removed)))
+
+	if !s.exists(s.DownloadPath) {
+		os.MkdirAll(s.DownloadPath, 0755) // first create directory
+	}
+
+	f, err := os.Create(filepath.Join(s.DownloadPath, changesFile))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(changes)
+}
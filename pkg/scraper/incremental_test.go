@@ -0,0 +1,68 @@
+package scraper_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/console"
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIncrementalSaveAttachment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	path := t.TempDir()
+
+	// Previous run
+	previous := []scraper.ManifestEntry{
+		{Path: "logo.png", URL: "https://example.com/logo.png", Size: 3, SHA256: "old", ETag: `"v1"`},
+		{Path: "style.css", URL: "https://example.com/style.css", Size: 3, SHA256: "old", LastModified: "Mon, 01 Jan 2024 00:00:00 GMT"},
+		{Path: "gone.pdf", URL: "https://example.com/gone.pdf", Size: 3, SHA256: "old"},
+	}
+	content, err := json.Marshal(previous)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(path, "manifest.json"), content, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(path, "logo.png"), []byte("png"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(path, "style.css"), []byte("css"), 0644))
+
+	mockHttpGet := get.NewMockHttpGet(ctrl)
+	mockHttpGet.EXPECT().Get("https://example.com/logo.png", http.Header{"If-None-Match": {`"v1"`}}).
+		Return("https://example.com/logo.png", http.StatusNotModified, new(bytes.Buffer), nil, nil)
+	mockHttpGet.EXPECT().Get("https://example.com/style.css", http.Header{"If-Modified-Since": {"Mon, 01 Jan 2024 00:00:00 GMT"}}).
+		Return("https://example.com/style.css", http.StatusOK, bytes.NewBufferString("new"), http.Header{"Etag": {`"v2"`}}, nil)
+	mockHttpGet.EXPECT().Get("https://example.com/new.pdf", http.Header(nil)).
+		Return("https://example.com/new.pdf", http.StatusOK, bytes.NewBufferString("pdf"), nil, nil)
+
+	mockObserver := console.NewMockObserver(ctrl)
+	mockObserver.EXPECT().Notify(gomock.Any()).AnyTimes()
+
+	s, err := scraper.NewFromConfig(&scraper.Config{OldDomain: "https://example.com", DownloadPath: path, Incremental: true}, mockHttpGet, mockObserver)
+	assert.NoError(t, err)
+
+	assert.NoError(t, s.SaveAttachment("https://example.com/logo.png"))
+	assert.NoError(t, s.SaveAttachment("https://example.com/style.css"))
+	assert.NoError(t, s.SaveAttachment("https://example.com/new.pdf"))
+
+	// Not modified files are kept, modified ones are saved again
+	logo, err := os.ReadFile(filepath.Join(path, "logo.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "png", string(logo))
+
+	style, err := os.ReadFile(filepath.Join(path, "style.css"))
+	assert.NoError(t, err)
+	assert.Equal(t, "new", string(style))
+	assert.Equal(t, `"v2"`, s.Manifest["style.css"].ETag)
+
+	assert.Equal(t, scraper.Changes{
+		New:       []string{"https://example.com/new.pdf"},
+		Changed:   []string{"https://example.com/style.css"},
+		Unchanged: []string{"https://example.com/logo.png"},
+		Removed:   []string{"https://example.com/gone.pdf"},
+	}, s.Changes())
+}
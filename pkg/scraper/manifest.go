@@ -0,0 +1,155 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Files of the integrity manifest, saved on the download path
+const (
+	checksumsFile = "SHA256SUMS"
+	manifestFile  = "manifest.json"
+)
+
+// ManifestEntry model, a file saved on the download path
+type ManifestEntry struct {
+	Path        string `json:"path"`
+	URL         string `json:"url"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	SHA256      string `json:"sha256"`
+
+	// Validators of the response, for conditional requests on the next run
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+
+	// Links and attachments found on a page, to crawl them on the next run
+	// even if the page is not modified
+	Links       []string `json:"links,omitempty"`
+	Attachments []string `json:"attachments,omitempty"`
+}
+
+// atomicWrite writes the content of r on a temporary file on the same folder,
+// renaming it to the given path only once it is complete, so a failed write
+// never leaves a half-written file behind
+func atomicWrite(final string, r io.Reader) (size int64, digest string, err error) {
+	f, err := os.CreateTemp(filepath.Dir(final), ".tmp-*")
+	if err != nil {
+		return
+	}
+
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	hash := sha256.New()
+	size, err = io.Copy(io.MultiWriter(f, hash), r)
+	if err != nil {
+		return
+	}
+
+	if err = f.Chmod(0644); err != nil {
+		return
+	}
+
+	if err = f.Close(); err != nil {
+		return
+	}
+
+	if err = os.Rename(f.Name(), final); err != nil {
+		return
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// writeFile writes the content of the given manifest entry atomically on the
+// given path, and records the entry on the manifest
+func (s *Scraper) writeFile(final string, entry ManifestEntry, r io.Reader) (err error) {
+	size, digest, err := atomicWrite(final, r)
+	if err != nil {
+		return
+	}
+
+	root, err := filepath.Abs(s.DownloadPath)
+	if err != nil {
+		return
+	}
+
+	rel, err := filepath.Rel(root, final)
+	if err != nil {
+		return
+	}
+
+	entry.Path = filepath.ToSlash(rel)
+	entry.Size = size
+	entry.SHA256 = digest
+	if entry.ContentType == "" {
+		entry.ContentType = mime.TypeByExtension(path.Ext(final))
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.Manifest[entry.Path] = entry
+
+	return
+}
+
+// ManifestEntries returns every file saved, sorted by path
+func (s *Scraper) ManifestEntries() (entries []ManifestEntry) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entries = make([]ManifestEntry, 0, len(s.Manifest))
+	for _, entry := range s.Manifest {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return
+}
+
+// SaveManifest saves the integrity manifest on the download path: a
+// SHA256SUMS file, that can be checked with sha256sum -c, and a JSON file
+// with the URL, size, content type and digest of every file
+func (s *Scraper) SaveManifest() (err error) {
+	if !s.exists(s.DownloadPath) {
+		os.MkdirAll(s.DownloadPath, 0755) // first create directory
+	}
+
+	entries := s.ManifestEntries()
+
+	var sums strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&sums, "%s  %s\n", entry.SHA256, entry.Path)
+	}
+
+	_, _, err = atomicWrite(filepath.Join(s.DownloadPath, checksumsFile), strings.NewReader(sums.String()))
+	if err != nil {
+		return
+	}
+
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return
+	}
+
+	_, _, err = atomicWrite(filepath.Join(s.DownloadPath, manifestFile), strings.NewReader(string(content)+"\n"))
+	return
+}
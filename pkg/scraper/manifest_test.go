@@ -0,0 +1,42 @@
+package scraper_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveManifest(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com"})
+	s.DownloadPath = t.TempDir()
+
+	content := "<html><body>About</body></html>"
+	assert.NoError(t, s.SaveHTML("https://example.com/about/", content))
+
+	sum := sha256.Sum256([]byte(content))
+	digest := hex.EncodeToString(sum[:])
+
+	assert.Equal(t, []scraper.ManifestEntry{
+		{Path: "about/index.html", URL: "https://example.com/about/", Size: int64(len(content)), ContentType: "text/html", SHA256: digest},
+	}, s.ManifestEntries())
+
+	// No temporary files are left behind
+	files, err := os.ReadDir(filepath.Join(s.DownloadPath, "about"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	assert.NoError(t, s.SaveManifest())
+
+	sums, err := os.ReadFile(filepath.Join(s.DownloadPath, "SHA256SUMS"))
+	assert.NoError(t, err)
+	assert.Equal(t, digest+"  about/index.html\n", string(sums))
+
+	manifest, err := os.ReadFile(filepath.Join(s.DownloadPath, "manifest.json"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(manifest), `"url": "https://example.com/about/"`)
+}
@@ -0,0 +1,144 @@
+package scraper
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Domain used for a mirror without a manifest to take it from
+const defaultMirrorDomain = "http://localhost"
+
+// Mirror maps the URLs of a downloaded site to the files they are saved on,
+// with the same rules the scraper saves them with
+type Mirror struct {
+	// Download path of the site
+	Path string
+
+	// Domain the site was downloaded from, empty if it is unknown
+	Domain string
+
+	// Content type of every file on the manifest, by its path
+	ContentTypes map[string]string
+
+	scraper *Scraper
+}
+
+// OpenMirror opens the site downloaded on the given path. If the domain is
+// empty, it is taken from the manifest. The names must have been made
+// portable on download if portable is set.
+func OpenMirror(downloadPath, domain string, portable bool) (*Mirror, error) {
+	entries, err := LoadManifest(downloadPath)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Mirror{Path: downloadPath, Domain: domain, ContentTypes: make(map[string]string)}
+	for _, entry := range entries {
+		m.ContentTypes[entry.Path] = entry.ContentType
+	}
+
+	if m.Domain == "" {
+		m.Domain = manifestDomain(entries)
+	}
+	m.Domain = RemoveLastSlash(m.Domain)
+
+	root := m.Domain
+	if root == "" {
+		root = defaultMirrorDomain
+	}
+
+	m.scraper = &Scraper{
+		OldDomain:     root,
+		Roots:         []string{root},
+		DownloadPath:  downloadPath,
+		PortablePaths: portable,
+	}
+
+	return m, nil
+}
+
+// manifestDomain returns the URL of the start page of the manifest, or the
+// site of its shortest URL if the start page is not on it
+func manifestDomain(entries map[string]ManifestEntry) string {
+	var shortest string
+	for _, entry := range entries {
+		if entry.Path == "index.html" && !strings.Contains(entry.URL, "?") {
+			return entry.URL
+		}
+		if shortest == "" || len(entry.URL) < len(shortest) {
+			shortest = entry.URL
+		}
+	}
+
+	return siteOf(shortest)
+}
+
+// siteOf returns the scheme and host of the given URL
+func siteOf(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// IsIntern checks if the given URL is on the site
+func (m *Mirror) IsIntern(link string) bool {
+	return m.scraper.IsInternLink(link)
+}
+
+// File returns the path of the file the given URL of the site is saved on,
+// relative to the download path
+func (m *Mirror) File(link string) string {
+	link, _, _ = strings.Cut(link, "#")
+
+	var folder, filename string
+	if m.scraper.IsValidAttachment(link) {
+		folder, filename = m.scraper.PreparePathsFile(link)
+	} else {
+		folder, filename = m.scraper.PreparePathsPage(link)
+	}
+
+	return relativePath(folder, filename)
+}
+
+// Resolve returns the file a request for the given URI is served with,
+// relative to the download path. The URI is mapped as a URL of the site
+// first, and looked up as a path of the download path otherwise, as the
+// links rewritten on download point to the files themselves. It returns
+// false if there is no such file.
+func (m *Mirror) Resolve(uri string) (string, bool) {
+	uri, _, _ = strings.Cut(uri, "#")
+	if !strings.HasPrefix(uri, "/") {
+		uri = "/" + uri
+	}
+
+	if local := m.File(m.scraper.OldDomain + uri); m.isFile(local) {
+		return local, true
+	}
+
+	rawPath, _, _ := strings.Cut(uri, "?")
+	unescaped, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return "", false
+	}
+
+	local := strings.TrimPrefix(path.Clean(unescaped), "/")
+	if m.isFile(local) {
+		return local, true
+	}
+	if index := path.Join(local, "index.html"); m.isFile(index) {
+		return index, true
+	}
+
+	return "", false
+}
+
+// isFile checks if the given path, relative to the download path, is a file
+func (m *Mirror) isFile(name string) bool {
+	info, err := os.Stat(filepath.Join(m.Path, filepath.FromSlash(name)))
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,61 @@
+package scraper_test
+
+import (
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMirror(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com"})
+	s.DownloadPath = t.TempDir()
+
+	assert.NoError(t, s.SaveHTML("https://example.com", "<html><body>Home</body></html>"))
+	assert.NoError(t, s.SaveHTML("https://example.com/about/", "<html><body>About</body></html>"))
+	assert.NoError(t, s.SaveHTML("https://example.com/list/?page=2", "<html><body>Page 2</body></html>"))
+	assert.NoError(t, s.SaveManifest())
+
+	m, err := scraper.OpenMirror(s.DownloadPath, "", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", m.Domain)
+	assert.Equal(t, "text/html", m.ContentTypes["about/index.html"])
+
+	assert.True(t, m.IsIntern("https://example.com/about/"))
+	assert.False(t, m.IsIntern("https://other.com/about/"))
+	assert.Equal(t, "about/index.html", m.File("https://example.com/about/#team"))
+	assert.Equal(t, "css/style.css", m.File("https://example.com/css/style.css"))
+
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"/", "index.html"},
+		{"/about/", "about/index.html"},
+		{"/about", "about/index.html"},
+		{"/about/index.html", "about/index.html"},
+		{"/list/?page=2", s.LocalURL("https://example.com/list/?page=2")[1:]},
+		{"/missing/", ""},
+		{"/../../etc/passwd", ""},
+	}
+	for _, tt := range tests {
+		got, ok := m.Resolve(tt.uri)
+		assert.Equal(t, tt.want, got, tt.uri)
+		assert.Equal(t, tt.want != "", ok, tt.uri)
+	}
+}
+
+func TestMirrorFolded(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com"})
+	s.DownloadPath = t.TempDir()
+
+	assert.NoError(t, s.SaveHTML("https://example.com/about/", "<html><body>about</body></html>"))
+	assert.NoError(t, s.SaveHTML("https://example.com/About/", "<html><body>About</body></html>"))
+	assert.NoError(t, s.SaveManifest())
+
+	// The name with upper case letters is the one with a hash
+	m, err := scraper.OpenMirror(s.DownloadPath, "https://example.com", false)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^About-[0-9a-f]{8}/index\.html$`, m.File("https://example.com/About/"))
+	assert.Equal(t, "about/index.html", m.File("https://example.com/about/"))
+}
@@ -0,0 +1,180 @@
+package scraper
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Query params stripped from the URLs by default: tracking params and session ids
+var DefaultStripParams = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "gbraid", "wbraid", "msclkid", "yclid", "mc_cid", "mc_eid", "_ga", "_gl",
+	"igshid", "ref_src", "jsessionid", "phpsessid", "aspsessionid*", "cfid", "cftoken", "sid", "sessionid",
+}
+
+// Normalizer normalizes URLs, so that the same resource is always found
+// under the same URL
+type Normalizer struct {
+	// Query params to remove. A trailing * matches any param with that prefix
+	StripParams []string
+
+	// If not empty, only these query params are kept, and StripParams is ignored
+	KeepParams []string
+}
+
+// NewNormalizer creates a Normalizer from comma separated lists of params
+func NewNormalizer(strip, keep string) *Normalizer {
+	return &Normalizer{
+		StripParams: SplitList(strip),
+		KeepParams:  SplitList(keep),
+	}
+}
+
+// Normalize lowercases the scheme and host, removes the default port, decodes
+// the percent-escapes of unreserved characters, filters the query params and
+// sorts them by key. Fragments are kept: they are not a concern of the normalizer.
+func (n *Normalizer) Normalize(link string) string {
+	u, err := url.Parse(decodeUnreserved(link))
+	if err != nil || u.Opaque != "" {
+		return link
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+
+	if u.RawQuery != "" {
+		u.RawQuery = n.filterQuery(u.RawQuery)
+	}
+	u.ForceQuery = false
+
+	return u.String()
+}
+
+// filterQuery removes the unwanted params from a raw query and sorts the rest
+// by key, keeping the original order of params with the same key
+func (n *Normalizer) filterQuery(query string) string {
+	type param struct {
+		key string
+		raw string
+	}
+
+	var params []param
+	for _, raw := range strings.Split(query, "&") {
+		if raw == "" {
+			continue
+		}
+
+		key, _, _ := strings.Cut(raw, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+
+		if !n.keepParam(key) {
+			continue
+		}
+
+		params = append(params, param{key: key, raw: raw})
+	}
+
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].key < params[j].key
+	})
+
+	kept := make([]string, len(params))
+	for i, p := range params {
+		kept[i] = p.raw
+	}
+
+	return strings.Join(kept, "&")
+}
+
+// keepParam checks if the query param with the given key must be kept
+func (n *Normalizer) keepParam(key string) bool {
+	if len(n.KeepParams) > 0 {
+		return matchParam(key, n.KeepParams)
+	}
+
+	return !matchParam(key, n.StripParams)
+}
+
+// matchParam checks if the key matches any of the patterns, case insensitive
+func matchParam(key string, patterns []string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(key, pattern[:len(pattern)-1]) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decodeUnreserved decodes the percent-escapes of unreserved characters, as
+// they are equivalent to the characters themselves, and uppercases the
+// hexadecimal digits of the rest
+func decodeUnreserved(link string) string {
+	if !strings.Contains(link, "%") {
+		return link
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(link); i++ {
+		if link[i] != '%' || i+2 >= len(link) || !isHex(link[i+1]) || !isHex(link[i+2]) {
+			b.WriteByte(link[i])
+			continue
+		}
+
+		c := unhex(link[i+1])<<4 | unhex(link[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(strings.ToUpper(link[i : i+3]))
+		}
+		i += 2
+	}
+
+	return b.String()
+}
+
+// isUnreserved checks if the character is unreserved, as defined by RFC 3986
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+// SplitList splits a comma separated list, trimming and skipping empty values
+func SplitList(list string) (values []string) {
+	for _, val := range strings.Split(list, ",") {
+		val = strings.TrimSpace(val)
+		if len(val) == 0 {
+			continue
+		}
+		values = append(values, val)
+	}
+
+	return
+}
@@ -0,0 +1,47 @@
+package scraper_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	n := scraper.NewNormalizer(strings.Join(scraper.DefaultStripParams, ","), "")
+
+	// Test scheme, host and port
+	assert.Equal(t, "http://example.com/Path", n.Normalize("HTTP://Example.COM/Path"))
+	assert.Equal(t, "http://example.com/path", n.Normalize("http://example.com:80/path"))
+	assert.Equal(t, "https://example.com/path", n.Normalize("https://example.com:443/path"))
+	assert.Equal(t, "http://example.com:443/path", n.Normalize("http://example.com:443/path"))
+	assert.Equal(t, "http://example.com:8080/path", n.Normalize("http://example.com:8080/path"))
+
+	// Test percent-escapes
+	assert.Equal(t, "http://example.com/~user/a-b", n.Normalize("http://example.com/%7Euser/%61%2Db"))
+	assert.Equal(t, "http://example.com/a%2Fb", n.Normalize("http://example.com/a%2fb"))
+	assert.Equal(t, "http://example.com/a%20b", n.Normalize("http://example.com/a%20b"))
+
+	// Test query params
+	assert.Equal(t, "http://example.com/path?a=1&b=2", n.Normalize("http://example.com/path?b=2&a=1"))
+	assert.Equal(t, "http://example.com/path?a=2&a=1", n.Normalize("http://example.com/path?a=2&utm_source=x&a=1"))
+	assert.Equal(t, "http://example.com/path?id=1", n.Normalize("http://example.com/path?id=1&fbclid=abc&GCLID=def&PHPSESSID=123"))
+	assert.Equal(t, "http://example.com/path", n.Normalize("http://example.com/path?utm_source=x&utm_medium=y"))
+	assert.Equal(t, "http://example.com/path", n.Normalize("http://example.com/path?"))
+}
+
+func TestNormalizeKeepParams(t *testing.T) {
+	n := scraper.NewNormalizer("", "page, id")
+
+	assert.Equal(t, "http://example.com/path?id=2&page=1", n.Normalize("http://example.com/path?page=1&sort=asc&id=2&utm_source=x"))
+	assert.Equal(t, "http://example.com/path", n.Normalize("http://example.com/path?sort=asc"))
+}
+
+func TestSanitizeURLWithQueries(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "http://example.com/", UseQueries: true, StripParams: "utm_*"})
+
+	assert.Equal(t, "http://example.com/path/?a=1&b=2", s.SanitizeURL("http://example.com/path?b=2&a=1#fragment"))
+	assert.Equal(t, "http://example.com/path/", s.SanitizeURL("http://example.com/path?utm_source=x"))
+	assert.Equal(t, "http://example.com/file.pdf?v=2", s.SanitizeURL("http://example.com/file.pdf?v=2"))
+}
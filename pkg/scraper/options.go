@@ -0,0 +1,251 @@
+package scraper
+
+import (
+	"strings"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
+)
+
+// Option configures a scraper created with New
+type Option func(*options)
+
+// options of a scraper being created
+type options struct {
+	conf        *Config
+	getter      HttpGet
+	middlewares []get.Middleware
+	observers   []Observer
+
+	transformers []Transformer
+}
+
+// New creates a new Scraper of the site on the given URL, configured with
+// the given options. Nothing is printed unless an observer printing the
+// events, like a console, is given.
+func New(url string, opts ...Option) (*Scraper, error) {
+	o := options{conf: DefaultConfig()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	o.conf.OldDomain = url
+	if err := validateFlags(o.conf); err != nil {
+		return nil, err
+	}
+
+	if o.getter == nil {
+		o.getter = get.New(append(o.conf.Middlewares(), o.middlewares...)...)
+	}
+
+	s, err := NewFromConfig(o.conf, o.getter, o.observers...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Transformers = o.transformers
+	return s, nil
+}
+
+// WithConfig starts from a copy of the given config instead of the default
+// one. It should be the first option, as it replaces the ones before it.
+func WithConfig(conf *Config) Option {
+	return func(o *options) {
+		c := *conf
+		o.conf = &c
+	}
+}
+
+// WithGetter fetches the URLs with the given getter instead of the default
+// HTTP client
+func WithGetter(getter HttpGet) Option {
+	return func(o *options) {
+		o.getter = getter
+	}
+}
+
+// WithMiddlewares sends every request through the given middlewares, in
+// order, inside the ones of the retries, the rate limit and the
+// authentication. They are ignored if a getter is given.
+func WithMiddlewares(middlewares ...get.Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithObservers notifies the given observers of every event
+func WithObservers(observers ...Observer) Option {
+	return func(o *options) {
+		o.observers = append(o.observers, observers...)
+	}
+}
+
+// WithTransformers changes the pages and files with the given transformers,
+// in order, before they are saved
+func WithTransformers(transformers ...Transformer) Option {
+	return func(o *options) {
+		o.transformers = append(o.transformers, transformers...)
+	}
+}
+
+// WithDownloadPath saves the site on the given path
+func WithDownloadPath(path string) Option {
+	return func(o *options) {
+		o.conf.DownloadPath = path
+	}
+}
+
+// WithNewDomain rewrites the links of the site to the given domain
+func WithNewDomain(domain string) Option {
+	return func(o *options) {
+		o.conf.NewDomain = domain
+	}
+}
+
+// WithRoots includes the URLs with the given prefixes too
+func WithRoots(roots ...string) Option {
+	return func(o *options) {
+		o.conf.IncludedURLs = strings.Join(append(strings.Split(o.conf.IncludedURLs, ","), roots...), ",")
+	}
+}
+
+// WithScope crawls the hosts of the given scope rules too, like
+// *.example.com, saving each on its own folder
+func WithScope(rules ...string) Option {
+	return func(o *options) {
+		o.conf.Scope = strings.Join(append(strings.Split(o.conf.Scope, ","), rules...), ",")
+	}
+}
+
+// WithConnections sets the number of concurrent connections
+func WithConnections(n int) Option {
+	return func(o *options) {
+		o.conf.Simultaneous = n
+	}
+}
+
+// WithRetries retries the requests that failed, or got a 429 or 5xx status,
+// up to the given number of times
+func WithRetries(retries int) Option {
+	return func(o *options) {
+		o.conf.Retries = retries
+	}
+}
+
+// WithRateLimit sends at most the given number of requests per second
+func WithRateLimit(perSecond float64) Option {
+	return func(o *options) {
+		o.conf.Rate = perSecond
+	}
+}
+
+// WithBasicAuth authenticates the requests to the site with the given user
+// and password
+func WithBasicAuth(user, password string) Option {
+	return func(o *options) {
+		o.conf.Auth = user + ":" + password
+	}
+}
+
+// WithPortablePaths maps the names to ones that are safe on Windows and
+// macOS too
+func WithPortablePaths() Option {
+	return func(o *options) {
+		o.conf.PortablePaths = true
+	}
+}
+
+// WithQueries keeps the query of the URLs. The given params are stripped
+// from them, replacing the default ones.
+func WithQueries(strip ...string) Option {
+	return func(o *options) {
+		o.conf.UseQueries = true
+		o.conf.StripParams = strings.Join(strip, ",")
+	}
+}
+
+// WithKeepParams keeps only the given query params on the URLs
+func WithKeepParams(params ...string) Option {
+	return func(o *options) {
+		o.conf.KeepParams = strings.Join(params, ",")
+	}
+}
+
+// WithCanonical saves the pages only once per canonical URL
+func WithCanonical() Option {
+	return func(o *options) {
+		o.conf.UseCanonical = true
+	}
+}
+
+// WithRedirectStubs saves stub pages on the old paths of redirected pages
+func WithRedirectStubs() Option {
+	return func(o *options) {
+		o.conf.RedirectStubs = true
+	}
+}
+
+// WithRedirectsFormat exports the redirects found in the given format
+func WithRedirectsFormat(format string) Option {
+	return func(o *options) {
+		o.conf.RedirectsFormat = format
+	}
+}
+
+// WithExternal downloads the page requisites on external hosts too, on the
+// external folder. If hosts are given, only the ones of those hosts are
+// downloaded. A *. prefix matches any subdomain.
+func WithExternal(hosts ...string) Option {
+	return func(o *options) {
+		o.conf.External = true
+		o.conf.ExternalAllow = strings.Join(hosts, ",")
+	}
+}
+
+// WithExternalDeny doesn't download the page requisites of the given hosts
+func WithExternalDeny(hosts ...string) Option {
+	return func(o *options) {
+		o.conf.ExternalDeny = strings.Join(hosts, ",")
+	}
+}
+
+// WithSanitize removes external scripts, iframes, tracking pixels, resource
+// hints and event handlers from the pages
+func WithSanitize() Option {
+	return func(o *options) {
+		o.conf.Sanitize = true
+	}
+}
+
+// WithIncremental re-crawls with conditional requests, based on the
+// previous run on the download path
+func WithIncremental() Option {
+	return func(o *options) {
+		o.conf.Incremental = true
+	}
+}
+
+// WithCheck checks the links of the site instead of saving it, and the
+// external links too if external is set. The broken links are on the result.
+func WithCheck(external bool) Option {
+	return func(o *options) {
+		o.conf.Check = true
+		o.conf.CheckExternal = external
+	}
+}
+
+// WithCheckReport writes the broken links report on the given file, in the
+// given format
+func WithCheckReport(name, format string) Option {
+	return func(o *options) {
+		o.conf.CheckOutput = name
+		o.conf.CheckFormat = format
+	}
+}
+
+// WithCrawlLog writes the crawl log on the given file, in the given format
+func WithCrawlLog(name, format string) Option {
+	return func(o *options) {
+		o.conf.CrawlLog = name
+		o.conf.CrawlLogFormat = format
+	}
+}
@@ -0,0 +1,173 @@
+package scraper
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// Maximum length in bytes of a file or folder name, on most filesystems
+	maxNameLength = 255
+
+	// Maximum length of an extension kept when a name is truncated
+	maxExtLength = 16
+)
+
+var (
+	// Characters not allowed on Windows filenames
+	windowsChars = `<>:"|?*`
+
+	// Names reserved by Windows, with or without extension
+	windowsNames = []string{
+		"con", "prn", "aux", "nul",
+		"com1", "com2", "com3", "com4", "com5", "com6", "com7", "com8", "com9",
+		"lpt1", "lpt2", "lpt3", "lpt4", "lpt5", "lpt6", "lpt7", "lpt8", "lpt9",
+	}
+)
+
+// shortHash returns a short hexadecimal hash of the given string
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:4])
+}
+
+// withHash appends the hash to the name, before its extension, truncating the
+// name so that the result is never longer than maxNameLength
+func withHash(name, hash string) string {
+	ext := path.Ext(name)
+	if len(ext) > maxExtLength || ext == name {
+		ext = ""
+	}
+
+	stem := strings.TrimSuffix(name, ext)
+	max := maxNameLength - len(ext) - len(hash) - 1
+	if len(stem) > max {
+		stem = stem[:max]
+		// Don't cut a multibyte character in half
+		for len(stem) > 0 && !utf8.ValidString(stem) {
+			stem = stem[:len(stem)-1]
+		}
+	}
+
+	return stem + "-" + hash + ext
+}
+
+// SafeName maps a segment of a URL path to a safe file or folder name. Names
+// that need to be changed, or that have upper case letters, get a hash of the
+// original name, so that different segments never end up on the same name.
+func (s *Scraper) SafeName(segment string) string {
+	name := segment
+	if unescaped, err := url.PathUnescape(segment); err == nil {
+		name = unescaped
+	}
+	original := name
+
+	// Never allow separators or control characters, on any system
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 0x20 || r == 0x7f {
+			return '_'
+		}
+		if s.PortablePaths && strings.ContainsRune(windowsChars, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	// Nor names that could traverse the folders
+	if name == "." || name == ".." {
+		name = "_"
+	}
+
+	if s.PortablePaths {
+		// Windows doesn't allow trailing dots and spaces
+		name = strings.TrimRight(name, ". ")
+		if name == "" {
+			name = "_"
+		}
+
+		stem, _, _ := strings.Cut(name, ".")
+		if IsInSlice(strings.ToLower(stem), windowsNames) {
+			name = "_" + name
+		}
+
+		// Names differing only in case are the same on Windows and macOS
+		name = strings.ToLower(name)
+	}
+
+	if name != original {
+		return withHash(name, shortHash(original))
+	}
+
+	// Names differing only in case are the same file on Windows and macOS:
+	// the ones with upper case letters get a hash, so they never overwrite
+	// the lower case one, whichever is saved first
+	if name != strings.ToLower(name) {
+		return withHash(name, shortHash(original))
+	}
+
+	if len(name) > maxNameLength {
+		return withHash(name, shortHash(original))
+	}
+
+	return name
+}
+
+// safePaths maps every segment of the folder and the filename to safe names
+func (s *Scraper) safePaths(folder, filename string) (string, string) {
+	if folder == "" && filename == "" {
+		return folder, filename
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(folder, "/") {
+		if segment == "" {
+			continue
+		}
+		segments = append(segments, s.SafeName(segment))
+	}
+
+	folder = "/"
+	if len(segments) > 0 {
+		folder = "/" + strings.Join(segments, "/") + "/"
+	}
+
+	if filename != "" {
+		filename = s.SafeName(filename)
+	}
+
+	return folder, filename
+}
+
+// localFile returns the folder and the full path where to save the given
+// folder and filename, making sure they never escape the download path
+func (s *Scraper) localFile(folder, filename string) (dir, final string, err error) {
+	root, err := filepath.Abs(s.DownloadPath)
+	if err != nil {
+		return
+	}
+
+	dir = filepath.Join(root, filepath.FromSlash(folder))
+	final = filepath.Join(dir, filename)
+
+	rel, err := filepath.Rel(root, final)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("path escapes the download path: %s", folder+filename)
+	}
+
+	return dir, final, nil
+}
+
+// escapePath escapes every segment of a local path to be used on a link
+func escapePath(local string) string {
+	segments := strings.Split(local, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
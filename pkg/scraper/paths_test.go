@@ -0,0 +1,119 @@
+package scraper_test
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSafeName(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com"})
+
+	// Test names kept as they are
+	assert.Equal(t, "about", s.SafeName("about"))
+	assert.Equal(t, "file.txt", s.SafeName("file.txt"))
+	assert.Equal(t, "hello world", s.SafeName("hello%20world"))
+	assert.Equal(t, "file:logo.png", s.SafeName("file:logo.png"))
+
+	// Test names with upper case letters, the same as the lower case ones on
+	// Windows and macOS
+	assert.Regexp(t, `^About-[0-9a-f]{8}$`, s.SafeName("About"))
+	assert.Regexp(t, `^File:Logo-[0-9a-f]{8}\.png$`, s.SafeName("File:Logo.png"))
+	assert.NotEqual(t, s.SafeName("About"), s.SafeName("ABOUT"))
+
+	// Test traversal and separators
+	assert.Regexp(t, `^_-[0-9a-f]{8}$`, s.SafeName(".."))
+	assert.Regexp(t, `^_-[0-9a-f]{8}$`, s.SafeName("%2e%2e"))
+	assert.Regexp(t, `^a_b-[0-9a-f]{8}$`, s.SafeName("a%2Fb"))
+	assert.Regexp(t, `^a_b-[0-9a-f]{8}$`, s.SafeName(`a\b`))
+	assert.NotEqual(t, s.SafeName("a%2Fb"), s.SafeName(`a\b`))
+
+	// Test long names
+	long := strings.Repeat("a", 300) + ".html"
+	assert.Len(t, s.SafeName(long), 255)
+	assert.Regexp(t, `^a+-[0-9a-f]{8}\.html$`, s.SafeName(long))
+	assert.NotEqual(t, s.SafeName(long), s.SafeName(strings.Repeat("a", 301)+".html"))
+	assert.True(t, len(s.SafeName(strings.Repeat("ñ", 200))) <= 255)
+}
+
+func TestSafeNamePortable(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com", PortablePaths: true})
+
+	assert.Equal(t, "about", s.SafeName("about"))
+	assert.Equal(t, "file.txt", s.SafeName("file.txt"))
+
+	// Test names differing only in case
+	assert.Regexp(t, `^about-[0-9a-f]{8}$`, s.SafeName("About"))
+	assert.NotEqual(t, s.SafeName("About"), s.SafeName("ABOUT"))
+
+	// Test Windows characters and reserved names
+	assert.Regexp(t, `^file_logo-[0-9a-f]{8}\.png$`, s.SafeName("File:Logo.png"))
+	assert.Regexp(t, `^_con-[0-9a-f]{8}$`, s.SafeName("CON"))
+	assert.Regexp(t, `^_aux-[0-9a-f]{8}\.txt$`, s.SafeName("aux.txt"))
+	assert.Regexp(t, `^name-[0-9a-f]{8}$`, s.SafeName("name."))
+	assert.Equal(t, "console", s.SafeName("console"))
+}
+
+func TestPreparePathsFolded(t *testing.T) {
+	// The same pages linked in a different order are saved on the same paths
+	saved := func(home string) (files []string) {
+		getter := site(t, map[string]string{
+			"https://example.com":        home,
+			"https://example.com/about/": `<html><body>about</body></html>`,
+			"https://example.com/About/": `<html><body>About</body></html>`,
+			"https://example.com/ABOUT/": `<html><body>ABOUT</body></html>`,
+		})
+
+		path := t.TempDir()
+		s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(path))
+		assert.NoError(t, err)
+		_, err = s.Run(context.Background())
+		assert.NoError(t, err)
+
+		filepath.WalkDir(path, func(name string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				rel, _ := filepath.Rel(path, name)
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return err
+		})
+		sort.Strings(files)
+		return
+	}
+
+	first := saved(`<html><body><a href="/About/">A</a><a href="/about/">a</a><a href="/ABOUT/">A</a></body></html>`)
+	second := saved(`<html><body><a href="/ABOUT/">A</a><a href="/about/">a</a><a href="/About/">A</a></body></html>`)
+	assert.Equal(t, first, second)
+	assert.Contains(t, first, "about/index.html")
+	assert.Len(t, first, 6)
+}
+
+func TestPreparePathsTraversal(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com"})
+
+	folder, filename := s.PreparePathsFile("https://example.com/%2e%2e/%2e%2e/etc/passwd.txt")
+	assert.Regexp(t, `^/_-[0-9a-f]{8}/_-[0-9a-f]{8}/etc/$`, folder)
+	assert.Equal(t, "passwd.txt", filename)
+
+	folder, filename = s.PreparePathsPage("https://example.com/a/..%2F..%2Fb/")
+	assert.Regexp(t, `^/a/[^/]+-[0-9a-f]{8}[^/]*/$`, folder)
+	assert.Equal(t, "index.html", filename)
+}
+
+func TestRewriteLinksMappedNames(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com", PortablePaths: true})
+	s.Get = get.New()
+
+	content := `<html><body><a href="/About/">About</a><a href="/contact/">Contact</a></body></html>`
+
+	rewritten := s.RewriteLinks("https://example.com/", content)
+	assert.Contains(t, rewritten, `href="/`+s.SafeName("About")+`/"`)
+	assert.Contains(t, rewritten, `href="/contact/"`)
+}
@@ -0,0 +1,393 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Maximum number of redirects followed for a single URL
+const maxRedirects = 10
+
+// Stub page saved on the old path of a redirected page
+const redirectStub = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Redirecting...</title>
+<link rel="canonical" href="%[1]s">
+<meta http-equiv="refresh" content="0; url=%[1]s">
+</head>
+<body>
+<p>This page has moved to <a href="%[1]s">%[1]s</a>.</p>
+</body>
+</html>
+`
+
+var (
+	// Formats the redirects can be exported to
+	RedirectsFormats = []string{"netlify", "nginx", "apache", "json", "csv"}
+
+	// Files the redirects are exported to, for each format
+	redirectsFiles = map[string]string{
+		"netlify": "_redirects",
+		"nginx":   "redirects.conf",
+		"apache":  ".htaccess",
+		"json":    "redirects.json",
+		"csv":     "redirects.csv",
+	}
+)
+
+// RedirectRule redirects an old path of the site to its new location
+type RedirectRule struct {
+	// Old path, with its query string, if any
+	From string `json:"from"`
+
+	// New location, a path if it is in the site or an absolute URL otherwise
+	To string `json:"to"`
+
+	// Status code of the redirection, 301 or 302
+	Status int `json:"status"`
+
+	// URLs of the full redirect chain, from the old to the new location
+	Chain []string `json:"chain"`
+}
+
+// IsRedirect checks if the status code is a redirection
+func IsRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// response model, the response of a followed link
+type response struct {
+	// Final URL, after following the redirects
+	URL    string
+	Status int
+	Body   *bytes.Buffer
+	Header http.Header
+
+	// Every hop from the given link to the final URL, if it was redirected
+	Redirects []Redirect
+}
+
+// follow gets the given link, following its redirects. The headers returned
+// by the header func, if any, are sent on the request of every URL.
+func follow(ctx context.Context, getter HttpGet, link string, header func(string) http.Header) (resp response, err error) {
+	resp.URL = link
+	for i := 0; i <= maxRedirects; i++ {
+		var reqHeader http.Header
+		if header != nil {
+			reqHeader = header(resp.URL)
+		}
+
+		var got string
+		if g, ok := getter.(contextGetter); ok {
+			got, resp.Status, resp.Body, resp.Header, err = g.GetContext(ctx, resp.URL, reqHeader)
+		} else {
+			got, resp.Status, resp.Body, resp.Header, err = getter.Get(resp.URL, reqHeader)
+		}
+		if err != nil {
+			return
+		}
+
+		if !IsRedirect(resp.Status) || got == resp.URL {
+			if len(resp.Redirects) == 0 {
+				resp.URL = got
+			}
+			return
+		}
+
+		resp.Redirects = append(resp.Redirects, Redirect{From: resp.URL, To: got, Status: resp.Status})
+		resp.URL = got
+	}
+
+	return resp, fmt.Errorf("too many redirects on %s", link)
+}
+
+// SaveRedirect saves a stub page on the path of the given URL that redirects
+// to the local path of the target URL
+func (s *Scraper) SaveRedirect(from, to string) (err error) {
+	folder, final, err := s.localFile(s.PreparePathsPage(from))
+	if err != nil {
+		return
+	}
+
+	// Never overwrite a downloaded page
+	if s.exists(final) {
+		return
+	}
+
+	if !s.exists(folder) {
+		os.MkdirAll(folder, 0755) // first create directory
+	}
+
+	// Targets on the site point to the file they are saved on, as the links
+	// of the pages do
+	target := to
+	if link := s.SanitizeURL(to); link != "" && s.IsInternLink(link) {
+		target = s.LocalURL(link)
+		if _, fragment, _ := strings.Cut(to, "#"); fragment != "" {
+			target += "#" + fragment
+		}
+	}
+
+	stub := fmt.Sprintf(redirectStub, html.EscapeString(target))
+	return s.writeFile(final, ManifestEntry{URL: from, ContentType: "text/html"}, strings.NewReader(stub))
+}
+
+// SaveRedirects saves a stub page for every redirected page found on the site
+func (s *Scraper) SaveRedirects() {
+	from := make([]string, 0, len(s.Redirects))
+	for link := range s.Redirects {
+		from = append(from, link)
+	}
+	sort.Strings(from)
+
+	for _, link := range from {
+		if !s.IsInternLink(link) || s.IsURLInSlice(link, s.Indexed) {
+			continue
+		}
+
+		chain := s.Redirects[link]
+		err := s.SaveRedirect(link, chain[len(chain)-1].To)
+		if err != nil {
+			s.fail(link, err)
+		}
+	}
+}
+
+// localPath returns the path of the given link, if it is in the site, or the
+// link itself otherwise
+func (s *Scraper) localPath(link string) string {
+	if !s.IsInternLink(link) {
+		return link
+	}
+
+	path := s.RemoveDomain(link)
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+
+	return path
+}
+
+// RedirectRules returns the rules for every redirected page of the site,
+// sorted by their old path
+func (s *Scraper) RedirectRules() (rules []RedirectRule) {
+	for link, chain := range s.Redirects {
+		if len(chain) == 0 || !s.IsInternLink(link) {
+			continue
+		}
+
+		rule := RedirectRule{
+			From:   s.localPath(link),
+			To:     s.localPath(chain[len(chain)-1].To),
+			Status: http.StatusMovedPermanently,
+			Chain:  []string{link},
+		}
+
+		for _, hop := range chain {
+			// A single temporary hop makes the whole redirection temporary
+			if hop.Status != http.StatusMovedPermanently && hop.Status != http.StatusPermanentRedirect {
+				rule.Status = http.StatusFound
+			}
+			rule.Chain = append(rule.Chain, hop.To)
+		}
+
+		// Redirects to the same path, like http to https, are useless on the new host
+		if rule.From == rule.To {
+			continue
+		}
+
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].From < rules[j].From
+	})
+
+	return
+}
+
+// ExportRedirects saves the redirects of the site on the download path, in
+// the given format
+func (s *Scraper) ExportRedirects(format string) (err error) {
+	filename, ok := redirectsFiles[format]
+	if !ok {
+		return fmt.Errorf("unknown redirects format: %s", format)
+	}
+
+	if !s.exists(s.DownloadPath) {
+		os.MkdirAll(s.DownloadPath, 0755) // first create directory
+	}
+
+	f, err := os.Create(filepath.Join(s.DownloadPath, filename))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	return WriteRedirects(f, format, s.RedirectRules())
+}
+
+// WriteRedirects writes the redirect rules in the given format
+func WriteRedirects(w io.Writer, format string, rules []RedirectRule) (err error) {
+	switch format {
+	case "netlify":
+		for _, rule := range rules {
+			// Netlify doesn't match the query on the path, but as params
+			// after it
+			from := rule.From
+			if path, query, found := strings.Cut(from, "?"); found {
+				from = strings.Join(append([]string{path}, strings.Split(query, "&")...), " ")
+			}
+			_, err = fmt.Fprintf(w, "%s %s %d\n", from, rule.To, rule.Status)
+			if err != nil {
+				return
+			}
+		}
+
+	case "nginx":
+		for _, rule := range rules {
+			path, query, _ := strings.Cut(rule.From, "?")
+			flag := "permanent"
+			if rule.Status != http.StatusMovedPermanently {
+				flag = "redirect"
+			}
+
+			// rewrite doesn't match query strings, so compare the full request URI
+			if query != "" {
+				_, err = fmt.Fprintf(w, "if ($request_uri = %s) { return %d %s; }\n", strconv.Quote(rule.From), rule.Status, strconv.Quote(rule.To))
+			} else {
+				_, err = fmt.Fprintf(w, "rewrite %s %s %s;\n", strconv.Quote("^"+regexp.QuoteMeta(path)+"$"), strconv.Quote(rule.To+"?"), flag)
+			}
+			if err != nil {
+				return
+			}
+		}
+
+	case "apache":
+		_, err = fmt.Fprintln(w, "RewriteEngine On")
+		if err != nil {
+			return
+		}
+		for _, rule := range rules {
+			path, query, _ := strings.Cut(rule.From, "?")
+			if query != "" {
+				_, err = fmt.Fprintf(w, "RewriteCond %%{QUERY_STRING} ^%s$\n", regexp.QuoteMeta(query))
+				if err != nil {
+					return
+				}
+			}
+
+			// Paths in .htaccess rules don't have the leading slash
+			pattern := "^" + regexp.QuoteMeta(strings.TrimPrefix(path, "/")) + "$"
+			_, err = fmt.Fprintf(w, "RewriteRule %s %s [R=%d,NE,L,QSD]\n", pattern, apacheTarget(rule.To), rule.Status)
+			if err != nil {
+				return
+			}
+		}
+
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if rules == nil {
+			rules = []RedirectRule{}
+		}
+		err = enc.Encode(rules)
+
+	case "csv":
+		c := csv.NewWriter(w)
+		err = c.Write([]string{"from", "to", "status"})
+		if err != nil {
+			return
+		}
+		for _, rule := range rules {
+			err = c.Write([]string{rule.From, rule.To, strconv.Itoa(rule.Status)})
+			if err != nil {
+				return
+			}
+		}
+		c.Flush()
+		err = c.Error()
+
+	default:
+		err = fmt.Errorf("unknown redirects format: %s", format)
+	}
+
+	return
+}
+
+// LoadRedirects reads the redirect rules exported on a previous run, from
+// the given download path or file, on the json or csv format
+func LoadRedirects(name string) (rules []RedirectRule, err error) {
+	if info, err := os.Stat(name); err == nil && info.IsDir() {
+		name = filepath.Join(name, redirectsFiles["json"])
+		if _, err := os.Stat(name); err != nil {
+			name = filepath.Join(filepath.Dir(name), redirectsFiles["csv"])
+		}
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(name, ".csv") {
+		return readRedirectsCSV(f)
+	}
+
+	err = json.NewDecoder(f).Decode(&rules)
+	return
+}
+
+// readRedirectsCSV reads the redirect rules of a CSV export
+func readRedirectsCSV(r io.Reader) (rules []RedirectRule, err error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return
+	}
+
+	for i, record := range records {
+		if i == 0 || len(record) < 3 {
+			continue
+		}
+
+		status, err := strconv.Atoi(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid status on line %d: %s", i+1, record[2])
+		}
+		rules = append(rules, RedirectRule{From: record[0], To: record[1], Status: status})
+	}
+
+	return
+}
+
+// apacheTarget escapes the target of a RewriteRule: $ and % would be taken as
+// back-references, and targets with spaces are quoted. The NE flag keeps the
+// target as it is written.
+func apacheTarget(to string) string {
+	to = strings.NewReplacer(`\`, `\\`, "$", `\$`, "%", `\%`, `"`, `\"`).Replace(to)
+	if strings.ContainsAny(to, " \t") {
+		return `"` + to + `"`
+	}
+
+	return to
+}
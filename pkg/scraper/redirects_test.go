@@ -0,0 +1,157 @@
+package scraper_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIsRedirect(t *testing.T) {
+	assert.True(t, scraper.IsRedirect(http.StatusMovedPermanently))
+	assert.True(t, scraper.IsRedirect(http.StatusFound))
+	assert.True(t, scraper.IsRedirect(http.StatusSeeOther))
+	assert.True(t, scraper.IsRedirect(http.StatusTemporaryRedirect))
+	assert.True(t, scraper.IsRedirect(http.StatusPermanentRedirect))
+
+	assert.False(t, scraper.IsRedirect(http.StatusOK))
+	assert.False(t, scraper.IsRedirect(http.StatusNotModified))
+	assert.False(t, scraper.IsRedirect(http.StatusNotFound))
+}
+
+func TestRunFollowsRedirects(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	mockHttpGet := get.NewMockHttpGet(ctrl)
+	mockHttpGet.EXPECT().Get("http://example.com/", gomock.Any()).Return("https://example.com/", http.StatusMovedPermanently, nil, nil, nil).Times(2)
+	mockHttpGet.EXPECT().Get("https://example.com/", gomock.Any()).Return("https://www.example.com/", http.StatusFound, nil, nil, nil).Times(2)
+	mockHttpGet.EXPECT().Get("https://www.example.com/", gomock.Any()).DoAndReturn(func(link string, header http.Header) (string, int, *bytes.Buffer, http.Header, error) {
+		return link, http.StatusOK, bytes.NewBufferString("<html></html>"), nil, nil
+	}).Times(2)
+	mockHttpGet.EXPECT().ParseURL(gomock.Any(), gomock.Any()).DoAndReturn(get.New().ParseURL).AnyTimes()
+
+	var domain string
+	observer := scraper.ObserverFunc(func(e scraper.Event) {
+		if e.Type == scraper.RunStarted {
+			domain = e.URL
+		}
+	})
+
+	// The domain is not got until the scraper is run
+	s, err := scraper.NewFromConfig(&scraper.Config{OldDomain: "http://example.com/", DownloadPath: t.TempDir(), Simultaneous: 1}, mockHttpGet, observer)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"http://example.com"}, s.Roots)
+
+	_, err = s.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.example.com", domain)
+	assert.Equal(t, []string{"https://www.example.com"}, s.Roots)
+	assert.Equal(t, []scraper.Redirect{
+		{From: "http://example.com/", To: "https://example.com/", Status: http.StatusMovedPermanently},
+		{From: "https://example.com/", To: "https://www.example.com/", Status: http.StatusFound},
+	}, s.Redirects["http://example.com/"])
+}
+
+func TestRunDomainError(t *testing.T) {
+	getter := site(t, map[string]string{})
+
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(t.TempDir()))
+	assert.NoError(t, err)
+
+	result, err := s.Run(context.Background())
+	assert.EqualError(t, err, "status code error: 404 on https://example.com")
+	assert.Empty(t, result.Pages)
+}
+
+func TestSaveRedirect(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com"})
+	s.DownloadPath = t.TempDir()
+
+	assert.NoError(t, s.SaveRedirect("https://example.com/old/", "https://example.com/new/"))
+
+	stub, err := os.ReadFile(filepath.Join(s.DownloadPath, "old", "index.html"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(stub), `<meta http-equiv="refresh" content="0; url=/new/">`)
+
+	// Downloaded pages are never overwritten
+	assert.NoError(t, os.WriteFile(filepath.Join(s.DownloadPath, "index.html"), []byte("home"), 0644))
+	assert.NoError(t, s.SaveRedirect("https://example.com/", "https://example.com/new/"))
+
+	home, err := os.ReadFile(filepath.Join(s.DownloadPath, "index.html"))
+	assert.NoError(t, err)
+	assert.Equal(t, "home", string(home))
+
+	// Targets point to the file they are saved on
+	s.UseQueries = true
+	target := "https://example.com/list/?page=2"
+	assert.NoError(t, s.SaveRedirect("https://example.com/list/page/2/", target))
+
+	stub, err = os.ReadFile(filepath.Join(s.DownloadPath, "list", "page", "2", "index.html"))
+	assert.NoError(t, err)
+	local := s.LocalURL(target)
+	assert.Contains(t, string(stub), `url=`+local+`">`)
+	assert.NotContains(t, string(stub), "?page=2")
+
+	assert.NoError(t, s.SaveHTML(target, "<html></html>"))
+	_, err = os.Stat(filepath.Join(s.DownloadPath, filepath.FromSlash(local)))
+	assert.NoError(t, err, local)
+}
+
+func TestRedirectRules(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com"})
+	s.Redirects = map[string][]scraper.Redirect{
+		"https://example.com/old/": {
+			{From: "https://example.com/old/", To: "https://example.com/new/", Status: http.StatusMovedPermanently},
+		},
+		"https://example.com/moved/": {
+			{From: "https://example.com/moved/", To: "https://example.com/tmp/", Status: http.StatusFound},
+			{From: "https://example.com/tmp/", To: "https://other.com/", Status: http.StatusMovedPermanently},
+		},
+		"https://other.com/old/": {
+			{From: "https://other.com/old/", To: "https://example.com/", Status: http.StatusMovedPermanently},
+		},
+	}
+
+	assert.Equal(t, []scraper.RedirectRule{
+		{From: "/moved/", To: "https://other.com/", Status: http.StatusFound, Chain: []string{"https://example.com/moved/", "https://example.com/tmp/", "https://other.com/"}},
+		{From: "/old/", To: "/new/", Status: http.StatusMovedPermanently, Chain: []string{"https://example.com/old/", "https://example.com/new/"}},
+	}, s.RedirectRules())
+}
+
+func TestWriteRedirects(t *testing.T) {
+	rules := []scraper.RedirectRule{
+		{From: "/old/", To: "/new/", Status: http.StatusMovedPermanently},
+		{From: "/page?id=1", To: "/page-1/", Status: http.StatusFound},
+		{From: "/offer?id=2&ref=mail", To: "/50% off/$1", Status: http.StatusMovedPermanently},
+	}
+
+	var tests = []struct {
+		Format   string
+		Expected string
+	}{
+		{Format: "netlify", Expected: "/old/ /new/ 301\n/page id=1 /page-1/ 302\n/offer id=2 ref=mail /50% off/$1 301\n"},
+		{Format: "nginx", Expected: "rewrite \"^/old/$\" \"/new/?\" permanent;\nif ($request_uri = \"/page?id=1\") { return 302 \"/page-1/\"; }\nif ($request_uri = \"/offer?id=2&ref=mail\") { return 301 \"/50% off/$1\"; }\n"},
+		{Format: "apache", Expected: "RewriteEngine On\nRewriteRule ^old/$ /new/ [R=301,NE,L,QSD]\nRewriteCond %{QUERY_STRING} ^id=1$\nRewriteRule ^page$ /page-1/ [R=302,NE,L,QSD]\n" +
+			"RewriteCond %{QUERY_STRING} ^id=2&ref=mail$\nRewriteRule ^offer$ \"/50\\% off/\\$1\" [R=301,NE,L,QSD]\n"},
+		{Format: "csv", Expected: "from,to,status\n/old/,/new/,301\n/page?id=1,/page-1/,302\n/offer?id=2&ref=mail,/50% off/$1,301\n"},
+	}
+
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		assert.NoError(t, scraper.WriteRedirects(buf, test.Format, rules))
+		assert.Equal(t, test.Expected, buf.String(), test.Format)
+	}
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, scraper.WriteRedirects(buf, "json", rules))
+	assert.Contains(t, buf.String(), `"from": "/page?id=1"`)
+
+	assert.Error(t, scraper.WriteRedirects(buf, "unknown", rules))
+}
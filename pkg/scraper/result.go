@@ -0,0 +1,99 @@
+package scraper
+
+import "time"
+
+// Result of a run
+type Result struct {
+	// Pages indexed, by their URL
+	Pages []string
+
+	// Files found, by their URL
+	Files []string
+
+	// Errors found during the run, as URLErrors when they are about a URL
+	Errors []error
+
+	// Broken links, grouped by the pages linking to them. Only set when
+	// checking the links.
+	Broken []PageLinks
+
+	Stats Stats
+}
+
+// Stats of a run
+type Stats struct {
+	// Pages and files fetched without errors
+	Pages int
+	Files int
+
+	// Errors found
+	Errors int
+
+	// Bytes fetched
+	Bytes int64
+
+	// Time the run started and took
+	Start    time.Time
+	Duration time.Duration
+}
+
+// URLError is an error found on a URL during a run
+type URLError struct {
+	URL string
+	Err error
+}
+
+// Error returns the error with its URL
+func (e *URLError) Error() string {
+	return e.URL + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error found on the URL
+func (e *URLError) Unwrap() error {
+	return e.Err
+}
+
+// Result returns the result of the run so far
+func (s *Scraper) Result() (result Result) {
+	if s.Check {
+		result.Broken = s.CheckedLinks(true)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := s.stats
+	stats.Start = s.StartTime
+	stats.Duration = time.Since(s.StartTime)
+
+	result.Pages = append([]string{}, s.Indexed...)
+	result.Files = append([]string{}, s.Files...)
+	result.Errors = append([]error{}, s.errors...)
+	result.Stats = stats
+
+	return
+}
+
+// count adds the event to the stats and errors of the run. The mutex must be
+// held.
+func (s *Scraper) count(e Event) {
+	switch e.Type {
+	case PageFetched:
+		if e.Err == nil {
+			s.stats.Pages++
+			s.stats.Bytes += e.Size
+		}
+	case AssetSaved:
+		if e.Err == nil {
+			s.stats.Files++
+			s.stats.Bytes += e.Size
+		}
+	case Error:
+		s.stats.Errors++
+		if e.URL != "" {
+			s.errors = append(s.errors, &URLError{URL: e.URL, Err: e.Err})
+		} else {
+			s.errors = append(s.errors, e.Err)
+		}
+	}
+}
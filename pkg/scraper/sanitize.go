@@ -0,0 +1,448 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Kinds of the elements and attributes removed by the sanitizer
+const (
+	RemovedScript   = "script"
+	RemovedLoader   = "loader"
+	RemovedFrame    = "iframe"
+	RemovedPixel    = "pixel"
+	RemovedHint     = "hint"
+	RemovedHandler  = "handler"
+	RemovedResource = "resource"
+	RemovedStyle    = "style"
+)
+
+// Kind of the inline scripts kept on the pages, reported as they may still
+// make requests
+const FlaggedScript = "inline-script"
+
+var (
+	// Resource hints that make the browser connect to their URL
+	resourceHints = []string{"preconnect", "dns-prefetch", "prefetch", "preload", "modulepreload", "prerender"}
+
+	// Attributes of the elements with the URL of a resource the browser
+	// requests, by tag
+	resourceAttributes = map[atom.Atom][]string{
+		atom.Img:    {"src", "srcset"},
+		atom.Source: {"src", "srcset"},
+		atom.Video:  {"src", "poster"},
+		atom.Audio:  {"src"},
+		atom.Track:  {"src"},
+		atom.Embed:  {"src"},
+		atom.Object: {"data"},
+		atom.Input:  {"src"},
+		atom.A:      {"ping"},
+		atom.Area:   {"ping"},
+	}
+
+	// Regexp to find the URLs on the string literals of scripts
+	urlsInScript = regexp.MustCompile("['\"`]((?:https?:)?//[^'\"`\\s]+)")
+
+	// Regexp to find the @import rules of CSS
+	importsInCSS = regexp.MustCompile(`@import\s+(?:url\(\s*)?['"]?([^'"\s)]+)['"]?\s*\)?[^;]*;?`)
+)
+
+// Removal model, an element or attribute removed from a page by the sanitizer
+type Removal struct {
+	Kind      string `json:"kind"`
+	Tag       string `json:"tag"`
+	URL       string `json:"url,omitempty"`
+	Attribute string `json:"attribute,omitempty"`
+}
+
+// SanitizedPage model, the removals of a page, and the inline scripts kept
+type SanitizedPage struct {
+	Page    string    `json:"page"`
+	Removed []Removal `json:"removed"`
+	Flagged []Removal `json:"flagged,omitempty"`
+}
+
+// sanitize removes from the document what makes the browser request URLs
+// outside the roots: scripts, and inline scripts loading them, iframes,
+// tracking pixels, resource hints, and every other resource, like
+// stylesheets, images and media, and the url() and @import of the styles. The
+// ones downloaded as external assets are kept. Every on* event handler is
+// removed too. It returns what was removed, and the inline scripts kept.
+func (s *Scraper) sanitize(pageURL string, doc *html.Node) (removed, flagged []Removal) {
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+
+			if c.Type == html.ElementNode {
+				if removal, ok := s.sanitizeElement(pageURL, c); ok {
+					removed = append(removed, removal)
+					n.RemoveChild(c)
+					c = next
+					continue
+				}
+
+				removed = append(removed, removeHandlers(c)...)
+				removed = append(removed, s.sanitizeResources(pageURL, c)...)
+				removed = append(removed, s.sanitizeStyles(pageURL, c)...)
+
+				if isInlineScript(c) {
+					flagged = append(flagged, Removal{Kind: FlaggedScript, Tag: c.Data})
+				}
+
+				if c.DataAtom == atom.Noscript {
+					r, fl := s.sanitizeNoscript(pageURL, c)
+					removed = append(removed, r...)
+					flagged = append(flagged, fl...)
+				}
+			}
+
+			f(c)
+			c = next
+		}
+	}
+	f(doc)
+
+	return
+}
+
+// sanitizeElement checks if the element must be removed, returning why
+func (s *Scraper) sanitizeElement(pageURL string, n *html.Node) (Removal, bool) {
+	switch n.DataAtom {
+	case atom.Script:
+		if link, ok := s.externalURL(pageURL, Attr(n, "src")); ok && !s.downloaded(link) {
+			return Removal{Kind: RemovedScript, Tag: n.Data, URL: link}, true
+		}
+		if link, ok := s.loadedURL(pageURL, n); ok {
+			return Removal{Kind: RemovedLoader, Tag: n.Data, URL: link}, true
+		}
+	case atom.Iframe, atom.Frame:
+		if link, ok := s.externalURL(pageURL, Attr(n, "src")); ok {
+			return Removal{Kind: RemovedFrame, Tag: n.Data, URL: link}, true
+		}
+	case atom.Img:
+		if link, ok := s.externalURL(pageURL, Attr(n, "src")); ok && isPixel(n) && !s.downloaded(link) {
+			return Removal{Kind: RemovedPixel, Tag: n.Data, URL: link}, true
+		}
+	case atom.Link:
+		for _, hint := range resourceHints {
+			if !HasRel(n, hint) {
+				continue
+			}
+			if link, ok := s.externalURL(pageURL, Attr(n, "href")); ok {
+				return Removal{Kind: RemovedHint, Tag: n.Data, URL: link}, true
+			}
+		}
+		if !isRequisiteLink(n) {
+			break
+		}
+		if link, ok := s.externalURL(pageURL, Attr(n, "href")); ok && !s.downloaded(link) {
+			return Removal{Kind: RemovedResource, Tag: n.Data, URL: link}, true
+		}
+	}
+
+	return Removal{}, false
+}
+
+// sanitizeResources removes the external URLs of the resources of the
+// element, like images, media and objects. Only the images are kept if they
+// are downloaded, as the only ones rewritten to their local copy.
+func (s *Scraper) sanitizeResources(pageURL string, n *html.Node) (removed []Removal) {
+	keys := resourceAttributes[n.DataAtom]
+	if len(keys) == 0 {
+		return
+	}
+
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if !IsInSlice(a.Key, keys) {
+			attrs = append(attrs, a)
+			continue
+		}
+
+		// Every candidate of srcset is checked apart
+		if a.Key == "srcset" {
+			var kept []string
+			for _, candidate := range strings.Split(a.Val, ",") {
+				ref, _, _ := strings.Cut(strings.TrimSpace(candidate), " ")
+				if link, ok := s.externalURL(pageURL, ref); ok {
+					removed = append(removed, Removal{Kind: RemovedResource, Tag: n.Data, URL: link, Attribute: a.Key})
+					continue
+				}
+				kept = append(kept, strings.TrimSpace(candidate))
+			}
+			if len(kept) > 0 {
+				a.Val = strings.Join(kept, ", ")
+				attrs = append(attrs, a)
+			}
+			continue
+		}
+
+		// Pings are sent to every URL of the list
+		if a.Key == "ping" {
+			var kept []string
+			for _, ref := range strings.Fields(a.Val) {
+				if link, ok := s.externalURL(pageURL, ref); ok {
+					removed = append(removed, Removal{Kind: RemovedResource, Tag: n.Data, URL: link, Attribute: a.Key})
+					continue
+				}
+				kept = append(kept, ref)
+			}
+			if len(kept) > 0 {
+				a.Val = strings.Join(kept, " ")
+				attrs = append(attrs, a)
+			}
+			continue
+		}
+
+		link, ok := s.externalURL(pageURL, a.Val)
+		if !ok || (n.DataAtom == atom.Img && s.downloaded(link)) {
+			attrs = append(attrs, a)
+			continue
+		}
+		removed = append(removed, Removal{Kind: RemovedResource, Tag: n.Data, URL: link, Attribute: a.Key})
+	}
+	n.Attr = attrs
+
+	return
+}
+
+// sanitizeStyles removes the external url() and @import of the style
+// attribute and the style element. The ones of the style attribute are kept
+// if they are downloaded, as the only ones rewritten to their local copy.
+func (s *Scraper) sanitizeStyles(pageURL string, n *html.Node) (removed []Removal) {
+	for i, a := range n.Attr {
+		if a.Key != "style" {
+			continue
+		}
+
+		css, urls := s.sanitizeCSS(pageURL, a.Val, true)
+		n.Attr[i].Val = css
+		for _, link := range urls {
+			removed = append(removed, Removal{Kind: RemovedStyle, Tag: n.Data, URL: link, Attribute: a.Key})
+		}
+	}
+
+	if n.DataAtom != atom.Style {
+		return
+	}
+
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.TextNode {
+			continue
+		}
+
+		css, urls := s.sanitizeCSS(pageURL, c.Data, false)
+		c.Data = css
+		for _, link := range urls {
+			removed = append(removed, Removal{Kind: RemovedStyle, Tag: n.Data, URL: link})
+		}
+	}
+
+	return
+}
+
+// sanitizeCSS removes the @import rules of external URLs from the CSS, and
+// replaces their url() with none, returning the URLs removed
+func (s *Scraper) sanitizeCSS(pageURL, css string, keepDownloaded bool) (string, []string) {
+	var removed []string
+
+	external := func(ref string) bool {
+		link, ok := s.externalURL(pageURL, ref)
+		if !ok || (keepDownloaded && s.downloaded(link)) {
+			return false
+		}
+		removed = append(removed, link)
+		return true
+	}
+
+	css = importsInCSS.ReplaceAllStringFunc(css, func(match string) string {
+		if external(importsInCSS.FindStringSubmatch(match)[1]) {
+			return ""
+		}
+		return match
+	})
+
+	css = urlsInCSS.ReplaceAllStringFunc(css, func(match string) string {
+		if external(urlsInCSS.FindStringSubmatch(match)[1]) {
+			return "none"
+		}
+		return match
+	})
+
+	return css, removed
+}
+
+// sanitizeNoscript sanitizes the content of a noscript element, which is
+// parsed as text
+func (s *Scraper) sanitizeNoscript(pageURL string, n *html.Node) (removed, flagged []Removal) {
+	text := n.FirstChild
+	if text == nil || text.Type != html.TextNode || text.NextSibling != nil {
+		return
+	}
+
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(text.Data), body)
+	if err != nil {
+		return
+	}
+	for _, node := range nodes {
+		body.AppendChild(node)
+	}
+
+	removed, flagged = s.sanitize(pageURL, body)
+	if len(removed) == 0 {
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	for c := body.FirstChild; c != nil; c = c.NextSibling {
+		if err := html.Render(buf, c); err != nil {
+			return nil, nil
+		}
+	}
+	text.Data = buf.String()
+
+	return
+}
+
+// removeHandlers removes the on* event handlers of the element
+func removeHandlers(n *html.Node) (removed []Removal) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if len(a.Key) > 2 && strings.HasPrefix(strings.ToLower(a.Key), "on") {
+			removed = append(removed, Removal{Kind: RemovedHandler, Tag: n.Data, Attribute: a.Key})
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+	n.Attr = attrs
+
+	return
+}
+
+// loadedURL checks if an inline script has an external URL on a string,
+// like the snippets that load the scripts of analytics and tag managers,
+// returning the first one
+func (s *Scraper) loadedURL(pageURL string, n *html.Node) (string, bool) {
+	if !isInlineScript(n) {
+		return "", false
+	}
+
+	for _, match := range urlsInScript.FindAllStringSubmatch(n.FirstChild.Data, -1) {
+		if link, ok := s.externalURL(pageURL, match[1]); ok {
+			return link, true
+		}
+	}
+
+	return "", false
+}
+
+// isInlineScript checks if the node is a script with its code inline
+func isInlineScript(n *html.Node) bool {
+	if n.DataAtom != atom.Script || Attr(n, "src") != "" {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(Attr(n, "type"))) {
+	case "", "module", "text/javascript", "application/javascript", "text/ecmascript", "application/ecmascript":
+	default:
+		return false
+	}
+
+	return n.FirstChild != nil && n.FirstChild.Type == html.TextNode && strings.TrimSpace(n.FirstChild.Data) != ""
+}
+
+// externalURL resolves the given reference of a page, and checks if it is a
+// URL outside the roots
+func (s *Scraper) externalURL(pageURL, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", false
+	}
+
+	link, err := s.Get.ParseURL(pageURL, ref)
+	if err != nil {
+		return "", false
+	}
+
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+
+	return link, !s.IsInternLink(link)
+}
+
+// downloaded checks if the external URL is downloaded as an external asset,
+// so it isn't requested from its host
+func (s *Scraper) downloaded(link string) bool {
+	_, ok := s.externalAsset(link)
+	return ok
+}
+
+// isPixel checks if an image is a tracking pixel: hidden, or not larger than
+// a single pixel
+func isPixel(n *html.Node) bool {
+	style := strings.ReplaceAll(strings.ToLower(Attr(n, "style")), " ", "")
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+
+	return isTiny(Attr(n, "width")) && isTiny(Attr(n, "height"))
+}
+
+// isTiny checks if a size attribute is set to 0 or 1 pixels
+func isTiny(size string) bool {
+	size = strings.TrimSuffix(strings.TrimSpace(size), "px")
+	return size == "0" || size == "1"
+}
+
+// SanitizedPages returns the pages with elements removed or inline scripts
+// flagged by the sanitizer, sorted by URL
+func (s *Scraper) SanitizedPages() (pages []SanitizedPage) {
+	for _, page := range s.Sanitized {
+		if page.Removed == nil {
+			page.Removed = []Removal{}
+		}
+		pages = append(pages, page)
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Page < pages[j].Page
+	})
+
+	return
+}
+
+// ExportSanitized saves the report of the elements removed by the sanitizer
+// on the download path
+func (s *Scraper) ExportSanitized() (err error) {
+	if !s.exists(s.DownloadPath) {
+		os.MkdirAll(s.DownloadPath, 0755) // first create directory
+	}
+
+	f, err := os.Create(filepath.Join(s.DownloadPath, "sanitized.json"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	pages := s.SanitizedPages()
+	if pages == nil {
+		pages = []SanitizedPage{}
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(pages)
+}
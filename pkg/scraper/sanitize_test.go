@@ -0,0 +1,147 @@
+package scraper_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+const tracked = `<html><head>` +
+	`<link rel="preconnect" href="https://fonts.gstatic.com">` +
+	`<link rel="preload" href="/style.css" as="style">` +
+	`<link rel="stylesheet" href="https://fonts.googleapis.com/css2?family=Roboto">` +
+	`<link rel="alternate" href="https://example.org/feed/">` +
+	`<script src="https://www.googletagmanager.com/gtag/js?id=G-1"></script>` +
+	`<script src="/app.js"></script>` +
+	`<script>var local = true;</script>` +
+	`<script>(function(w,d){var j=d.createElement('script');j.src='https://www.googletagmanager.com/gtm.js?id=GTM-1';d.head.appendChild(j)})(window,document);</script>` +
+	`<script type="application/ld+json">{"url": "https://example.org/"}</script>` +
+	`<style>@import url("https://cdn.example.org/theme.css"); body { background: url(https://cdn.example.org/bg.png) } h1 { background: url(/local.png) }</style>` +
+	`</head><body onload="track()">` +
+	`<iframe src="https://www.youtube.com/embed/1"></iframe>` +
+	`<iframe src="/embed/"></iframe>` +
+	`<img src="https://pixel.example.org/p.gif" width="1" height="1">` +
+	`<img src="https://cdn.example.org/photo.jpg" width="640" height="480" alt="Photo">` +
+	`<img src="/photo.jpg" srcset="/photo-2x.jpg 2x, https://cdn.example.org/photo-3x.jpg 3x">` +
+	`<video poster="https://cdn.example.org/poster.jpg"><source src="https://cdn.example.org/movie.mp4"><source src="/movie.webm"></video>` +
+	`<object data="https://cdn.example.org/doc.pdf"></object><embed src="https://cdn.example.org/anim.swf">` +
+	`<div style="background: url('https://cdn.example.org/hero.jpg')"></div>` +
+	`<a href="https://other.com/" onclick="track()" ping="/ping https://track.example.org/ping">Other</a>` +
+	`<noscript><img height="1" width="1" style="display:none" src="https://www.facebook.com/tr?id=1"/></noscript>` +
+	`</body></html>`
+
+func TestSanitize(t *testing.T) {
+	getter := site(t, map[string]string{
+		"https://example.com": tracked,
+	})
+
+	path := t.TempDir()
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(path), scraper.WithSanitize())
+	assert.NoError(t, err)
+
+	_, err = s.Run(context.Background())
+	assert.NoError(t, err)
+
+	saved, err := os.ReadFile(filepath.Join(path, "index.html"))
+	assert.NoError(t, err)
+	page := string(saved)
+
+	for _, removed := range []string{"fonts.gstatic.com", "fonts.googleapis.com", "googletagmanager", "youtube", "pixel.example.org", "cdn.example.org", "track.example.org", "facebook", "onload", "onclick"} {
+		assert.NotContains(t, page, removed)
+	}
+	for _, kept := range []string{
+		`href="/style.css"`, `href="https://example.org/feed/"`, `src="/app.js"`, "var local = true;", `"url": "https://example.org/"`,
+		"body { background: none }", "url(/local.png)", `src="/embed/"`, `alt="Photo"`, `srcset="/photo-2x.jpg 2x"`,
+		`<source src="/movie.webm"/>`, `href="https://other.com/"`, `ping="/ping"`, "<noscript>",
+	} {
+		assert.Contains(t, page, kept)
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, "sanitized.json"))
+	assert.NoError(t, err)
+
+	var report []scraper.SanitizedPage
+	assert.NoError(t, json.Unmarshal(data, &report))
+	assert.Len(t, report, 1)
+	assert.Equal(t, "https://example.com", report[0].Page)
+	assert.Equal(t, []scraper.Removal{
+		{Kind: scraper.RemovedHint, Tag: "link", URL: "https://fonts.gstatic.com"},
+		{Kind: scraper.RemovedResource, Tag: "link", URL: "https://fonts.googleapis.com/css2?family=Roboto"},
+		{Kind: scraper.RemovedScript, Tag: "script", URL: "https://www.googletagmanager.com/gtag/js?id=G-1"},
+		{Kind: scraper.RemovedLoader, Tag: "script", URL: "https://www.googletagmanager.com/gtm.js?id=GTM-1"},
+		{Kind: scraper.RemovedStyle, Tag: "style", URL: "https://cdn.example.org/theme.css"},
+		{Kind: scraper.RemovedStyle, Tag: "style", URL: "https://cdn.example.org/bg.png"},
+		{Kind: scraper.RemovedHandler, Tag: "body", Attribute: "onload"},
+		{Kind: scraper.RemovedFrame, Tag: "iframe", URL: "https://www.youtube.com/embed/1"},
+		{Kind: scraper.RemovedPixel, Tag: "img", URL: "https://pixel.example.org/p.gif"},
+		{Kind: scraper.RemovedResource, Tag: "img", URL: "https://cdn.example.org/photo.jpg", Attribute: "src"},
+		{Kind: scraper.RemovedResource, Tag: "img", URL: "https://cdn.example.org/photo-3x.jpg", Attribute: "srcset"},
+		{Kind: scraper.RemovedResource, Tag: "video", URL: "https://cdn.example.org/poster.jpg", Attribute: "poster"},
+		{Kind: scraper.RemovedResource, Tag: "source", URL: "https://cdn.example.org/movie.mp4", Attribute: "src"},
+		{Kind: scraper.RemovedResource, Tag: "object", URL: "https://cdn.example.org/doc.pdf", Attribute: "data"},
+		{Kind: scraper.RemovedResource, Tag: "embed", URL: "https://cdn.example.org/anim.swf", Attribute: "src"},
+		{Kind: scraper.RemovedStyle, Tag: "div", URL: "https://cdn.example.org/hero.jpg", Attribute: "style"},
+		{Kind: scraper.RemovedHandler, Tag: "a", Attribute: "onclick"},
+		{Kind: scraper.RemovedResource, Tag: "a", URL: "https://track.example.org/ping", Attribute: "ping"},
+		{Kind: scraper.RemovedPixel, Tag: "img", URL: "https://www.facebook.com/tr?id=1"},
+	}, report[0].Removed)
+
+	// Inline scripts kept are flagged
+	assert.Equal(t, []scraper.Removal{{Kind: scraper.FlaggedScript, Tag: "script"}}, report[0].Flagged)
+}
+
+// Requisites downloaded as external assets are kept, pointing to their local
+// copy
+func TestSanitizeExternal(t *testing.T) {
+	getter, fetched := typedSite(t, map[string][2]string{
+		"https://example.com":                             {"text/html", tracked},
+		"https://fonts.googleapis.com/css2?family=Roboto": {"text/css", "body {}"},
+		"https://cdn.example.org/photo.jpg":               {"image/jpeg", "jpg"},
+		"https://cdn.example.org/hero.jpg":                {"image/jpeg", "jpg"},
+	})
+
+	path := t.TempDir()
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(path), scraper.WithSanitize(),
+		scraper.WithExternal("fonts.googleapis.com", "cdn.example.org"))
+	assert.NoError(t, err)
+
+	_, err = s.Run(context.Background())
+	assert.NoError(t, err)
+
+	saved, err := os.ReadFile(filepath.Join(path, "index.html"))
+	assert.NoError(t, err)
+	page := string(saved)
+
+	assert.Contains(t, page, `src="/_external/cdn.example.org/photo.jpg"`)
+	assert.Contains(t, page, "url(/_external/cdn.example.org/hero.jpg)")
+	assert.Contains(t, page, `href="/_external/fonts.googleapis.com/`)
+
+	// The ones not rewritten are removed even from allowed hosts
+	assert.NotContains(t, page, "https://cdn.example.org")
+	assert.NotContains(t, *fetched, "https://cdn.example.org/movie.mp4")
+}
+
+func TestSanitizeOff(t *testing.T) {
+	getter := site(t, map[string]string{
+		"https://example.com": tracked,
+	})
+
+	path := t.TempDir()
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(path))
+	assert.NoError(t, err)
+
+	_, err = s.Run(context.Background())
+	assert.NoError(t, err)
+
+	saved, err := os.ReadFile(filepath.Join(path, "index.html"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(saved), "googletagmanager")
+
+	_, err = os.Stat(filepath.Join(path, "sanitized.json"))
+	assert.True(t, os.IsNotExist(err))
+}
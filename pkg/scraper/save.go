@@ -2,14 +2,55 @@ package scraper
 
 import (
 	"bytes"
-	"io"
+	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
+
+	"golang.org/x/net/html"
 )
 
-// PreparePathsFile prepares the folder and filename for a given URL, assuming it's a file
+// Attributes with links to rewrite on the saved HTML
+var linkAttributes = []string{"href", "src"}
+
+// PreparePathsFile prepares the folder and filename for a given URL, assuming it's a file.
+// Every name is mapped to a safe one, and URLs with a query get a short hash
+// of it appended to the filename.
 func (s *Scraper) PreparePathsFile(url string) (folder, filename string) {
+	local, external := s.externalPath(url)
+
+	url, query, _ := strings.Cut(url, "?")
+	if external {
+		folder, filename = s.safePaths(s.GetPath(local), local[strings.LastIndex(local, "/")+1:])
+	} else {
+		folder, filename = s.safePaths(s.preparePathsFile(url))
+	}
+
+	return folder, QueryFilename(filename, query)
+}
+
+// PreparePathsPage prepares the folder and filename for a given URL, assuming it's a page.
+// Every name is mapped to a safe one, and URLs with a query get a short hash
+// of it appended to the filename.
+func (s *Scraper) PreparePathsPage(url string) (folder, filename string) {
+	url, query, _ := strings.Cut(url, "?")
+	folder, filename = s.safePaths(s.preparePathsPage(url))
+	return folder, QueryFilename(filename, query)
+}
+
+// QueryFilename appends a short hash of the query to the filename, before its
+// extension, so every query variant of a URL gets its own safe filename
+func QueryFilename(filename, query string) string {
+	if query == "" {
+		return filename
+	}
+
+	return withHash(filename, shortHash(query))
+}
+
+// preparePathsFile prepares the folder and filename for a given URL without query
+func (s *Scraper) preparePathsFile(url string) (folder, filename string) {
 	url = s.RemoveDomain(url)
 	if url == "" {
 		return "", ""
@@ -32,8 +73,8 @@ func (s *Scraper) PreparePathsFile(url string) (folder, filename string) {
 	return
 }
 
-// PreparePathsPage prepares the folder and filename for a given URL, assuming it's a page
-func (s *Scraper) PreparePathsPage(url string) (folder, filename string) {
+// preparePathsPage prepares the folder and filename for a given URL without query
+func (s *Scraper) preparePathsPage(url string) (folder, filename string) {
 	url = s.RemoveDomain(url)
 	if url == "" {
 		return "/", "index.html"
@@ -72,57 +113,250 @@ func (s *Scraper) PreparePathsPage(url string) (folder, filename string) {
 
 // Download a single link
 func (s *Scraper) SaveAttachment(url string) (err error) {
-	folder, filename := s.PreparePathsFile(url)
-	folder = s.DownloadPath + folder
-	final := folder + filename
+	event := s.fetched(AssetSaved, url, response{}, 0)
+	defer func() {
+		event.Err = err
+		s.emit(event)
+	}()
+
+	folder, final, err := s.localFile(s.PreparePathsFile(url))
+	if err != nil {
+		return
+	}
 
 	if !s.exists(folder) {
 		os.MkdirAll(folder, 0755) // first create directory
 	}
 
-	resp, err := http.Get(url)
+	resp, elapsed, err := s.fetch(url, s.conditionalHeader)
+
+	event = s.fetched(AssetSaved, url, resp, elapsed)
+	event.Path = relativePath(s.PreparePathsFile(url))
+
 	if err != nil {
 		return
 	}
-	defer resp.Body.Close()
 
-	f, err := os.Create(final)
-	if err != nil {
+	// Not modified since the previous run: keep the file
+	if resp.Status == http.StatusNotModified && s.keepFile(url) {
 		return
 	}
-	defer f.Close()
 
-	_, err = io.Copy(f, resp.Body)
-	return
+	if resp.Status != http.StatusOK {
+		return fmt.Errorf("status code error: %d on %s", resp.Status, url)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	body := resp.Body.Bytes()
+
+	// Point the stylesheets to the external assets saved
+	if s.External && s.isCSS(url, contentType) {
+		body = []byte(s.rewriteCSS(resp.URL, string(body)))
+	}
+
+	content, err := s.transform(url, contentType, body)
+	if err != nil {
+		return fmt.Errorf("error transforming %s: %s", url, err)
+	}
+
+	return s.writeFile(final, ManifestEntry{
+		URL:          url,
+		ContentType:  contentType,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}, bytes.NewReader(content))
 }
 
 // Download a single link
 func (s *Scraper) SaveHTML(url string, html string) (err error) {
-	folder, filename := s.PreparePathsPage(url)
-	folder = s.DownloadPath + folder
-	final := folder + filename
+	return s.savePage(ManifestEntry{URL: url}, url, html)
+}
+
+// SavePage saves a scraped page on the path of the given URL
+func (s *Scraper) SavePage(url string, page Page) (err error) {
+	entry := ManifestEntry{
+		URL:          url,
+		ETag:         page.ETag,
+		LastModified: page.LastModified,
+		Attachments:  page.Attachments,
+	}
+	for _, link := range page.Links {
+		entry.Links = append(entry.Links, link.Href)
+	}
+
+	return s.savePage(entry, page.URL, page.HTML)
+}
+
+// savePage saves the HTML of a page, fetched from the given URL, recording
+// the given manifest entry. Its links are resolved against the URL it was
+// fetched from, which may be on another folder, like for canonical pages.
+func (s *Scraper) savePage(entry ManifestEntry, fetched, html string) (err error) {
+	folder, final, err := s.localFile(s.PreparePathsPage(entry.URL))
+	if err != nil {
+		return
+	}
 
 	if !s.exists(folder) {
 		os.MkdirAll(folder, 0755) // first create directory
 	}
 
-	f, err := os.Create(final)
+	transformed, err := s.transform(entry.URL, "text/html", []byte(html))
 	if err != nil {
-		return
+		return fmt.Errorf("error transforming %s: %s", entry.URL, err)
 	}
-	defer f.Close()
+
+	html = s.rewriteLinks(fetched, fetched != entry.URL, string(transformed))
 
 	for _, root := range s.Roots {
 		html = strings.ReplaceAll(html, root, "")
 	}
 
 	if s.NewDomain != "" && s.OldDomain != s.NewDomain {
-		newStr := strings.ReplaceAll(html, s.OldDomain, s.NewDomain)
-		newContent := bytes.NewBufferString(newStr)
-		_, err = io.Copy(f, newContent)
+		html = strings.ReplaceAll(html, s.OldDomain, s.NewDomain)
+	}
+
+	entry.ContentType = "text/html"
+	return s.writeFile(final, entry, strings.NewReader(html))
+}
+
+// LocalURL returns the escaped path a link is served on from the download path
+func (s *Scraper) LocalURL(link string) string {
+	var folder, filename string
+	if s.IsValidAttachment(link) || s.isExternal(link) {
+		folder, filename = s.PreparePathsFile(link)
 	} else {
-		_, err = io.Copy(f, bytes.NewBufferString(html))
+		folder, filename = s.PreparePathsPage(link)
 	}
 
-	return
+	// Pages saved as index.html are served on their folder
+	if filename == "index.html" {
+		return escapePath(folder)
+	}
+
+	return escapePath(folder + filename)
+}
+
+// RewriteLinks rewrites the links of the given page that are saved on a
+// different path than the one on their URL, like the ones with a query or
+// with unsafe names. The page is returned untouched if there are none.
+func (s *Scraper) RewriteLinks(pageURL string, content string) string {
+	return s.rewriteLinks(pageURL, false, content)
+}
+
+// rewriteLinks rewrites the links of the given page. If it is saved on
+// another path than the one of its URL, every relative link is rewritten too,
+// to the local path of the site or the URL it points to.
+func (s *Scraper) rewriteLinks(pageURL string, moved bool, content string) string {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return content
+	}
+
+	rewritten := false
+
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for i, a := range n.Attr {
+				if a.Key == "style" && s.External {
+					if css := s.rewriteCSS(pageURL, a.Val); css != a.Val {
+						n.Attr[i].Val = css
+						rewritten = true
+					}
+					continue
+				}
+
+				if !IsInSlice(a.Key, linkAttributes) {
+					continue
+				}
+
+				link, err := s.Get.ParseURL(pageURL, a.Val)
+				if err != nil {
+					continue
+				}
+
+				// Assets of external hosts are saved on the external folder
+				if asset, ok := s.externalAsset(link); ok && s.isExternal(asset) {
+					n.Attr[i].Val = s.LocalURL(asset)
+					rewritten = true
+					continue
+				}
+
+				// Links to the fragments of the page itself stay as they are
+				relative := moved && !isAbsolute(a.Val) && !strings.HasPrefix(strings.TrimSpace(a.Val), "#")
+
+				resolved := link
+				link = s.SanitizeURL(link)
+				if link == "" {
+					continue
+				}
+				if !s.IsInternLink(link) {
+					if relative {
+						n.Attr[i].Val = resolved
+						rewritten = true
+					}
+					continue
+				}
+
+				// Links only in the scope are always rewritten, as they
+				// aren't stripped of their root
+				local := s.LocalURL(link)
+				if !relative && s.underRoot(link) && pathsEqual(local, s.RemoveDomain(link)) {
+					continue
+				}
+
+				_, fragment, _ := strings.Cut(a.Val, "#")
+				if fragment != "" {
+					local += "#" + fragment
+				}
+
+				n.Attr[i].Val = local
+				rewritten = true
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(doc)
+
+	if !rewritten {
+		return content
+	}
+
+	buf := new(bytes.Buffer)
+	if err := html.Render(buf, doc); err != nil {
+		return content
+	}
+
+	return buf.String()
+}
+
+// pathsEqual checks if a local path is the same as the path of a URL
+func pathsEqual(local, link string) bool {
+	if link == "" {
+		link = "/"
+	}
+
+	if unescaped, err := url.PathUnescape(local); err == nil {
+		local = unescaped
+	}
+	if unescaped, err := url.PathUnescape(link); err == nil {
+		link = unescaped
+	}
+
+	return local == link
+}
+
+// isAbsolute checks if a reference is a URL with a scheme, or a path from the
+// root, so it points to the same place from any folder
+func isAbsolute(ref string) bool {
+	ref = strings.TrimSpace(ref)
+	if strings.HasPrefix(ref, "/") {
+		return true
+	}
+
+	u, err := url.Parse(ref)
+	return err == nil && u.Scheme != ""
 }
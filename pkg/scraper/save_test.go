@@ -3,7 +3,9 @@ package scraper_test
 import (
 	"testing"
 
+	"github.com/antsanchez/go-download-web/pkg/get"
 	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
 )
 
 type Path struct {
@@ -64,3 +66,46 @@ func TestPreparePathsPage(t *testing.T) {
 		}
 	}
 }
+
+// Test for PreparePathsPage and PreparePathsFile with queries
+func TestPreparePathsQuery(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com", UseQueries: true})
+
+	folder, filename := s.PreparePathsPage("https://example.com/search/?page=2&q=go")
+	assert.Equal(t, "/search/", folder)
+	assert.Regexp(t, `^index-[0-9a-f]{8}\.html$`, filename)
+
+	// Same query, same filename. Different query, different filename
+	_, again := s.PreparePathsPage("https://example.com/search/?page=2&q=go")
+	_, other := s.PreparePathsPage("https://example.com/search/?page=3&q=go")
+	assert.Equal(t, filename, again)
+	assert.NotEqual(t, filename, other)
+
+	folder, filename = s.PreparePathsPage("https://example.com/feed.php?id=1")
+	assert.Equal(t, "/", folder)
+	assert.Regexp(t, `^feed-[0-9a-f]{8}\.php$`, filename)
+
+	folder, filename = s.PreparePathsFile("https://example.com/css/style.css?v=3")
+	assert.Equal(t, "/css/", folder)
+	assert.Regexp(t, `^style-[0-9a-f]{8}\.css$`, filename)
+}
+
+func TestRewriteLinks(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://example.com", UseQueries: true})
+	s.Get = get.New()
+
+	_, page := s.PreparePathsPage("https://example.com/search/?page=2&q=go")
+	_, file := s.PreparePathsFile("https://example.com/style.css?v=3")
+
+	content := `<html><head><link rel="stylesheet" href="/style.css?v=3"></head>` +
+		`<body><a href="/search?q=go&amp;page=2#results">Next</a><a href="https://other.com/?a=1">Other</a></body></html>`
+
+	rewritten := s.RewriteLinks("https://example.com/", content)
+	assert.Contains(t, rewritten, `href="/`+file+`"`)
+	assert.Contains(t, rewritten, `href="/search/`+page+`#results"`)
+	assert.Contains(t, rewritten, `href="https://other.com/?a=1"`)
+
+	// Pages without query links are not touched
+	plain := `<html><body><a href="/about/">About</a></body></html>`
+	assert.Equal(t, plain, s.RewriteLinks("https://example.com/", plain))
+}
@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
+)
+
+// ScopeRule model, a host, and optionally a path prefix, whose pages are
+// crawled. The scheme is ignored, and www. matches the apex domain.
+type ScopeRule struct {
+	// Host without www., or a *. wildcard matching it and its subdomains
+	Host string
+
+	// Path prefix of the links, if any
+	Path string
+}
+
+// ParseScope parses a scope rule, like example.com, *.example.com or
+// https://example.com/docs
+func ParseScope(rule string) (ScopeRule, error) {
+	rule = strings.TrimSpace(rule)
+	if _, after, found := strings.Cut(rule, "://"); found {
+		rule = after
+	}
+
+	host, path, found := strings.Cut(rule, "/")
+	if found {
+		path = "/" + path
+	}
+
+	host = siteHost(host)
+	name := strings.TrimPrefix(host, "*.")
+	if name == "" || strings.ContainsAny(name, "*?#") {
+		return ScopeRule{}, fmt.Errorf("%q is not a host or a *. wildcard", rule)
+	}
+
+	return ScopeRule{Host: host, Path: RemoveLastSlash(path)}, nil
+}
+
+// ParseScopes parses the given comma separated scope rules
+func ParseScopes(rules string) (scope []ScopeRule, err error) {
+	for _, rule := range SplitList(rules) {
+		r, err := ParseScope(rule)
+		if err != nil {
+			return nil, err
+		}
+		scope = append(scope, r)
+	}
+
+	return
+}
+
+// Match checks if the link is in the scope of the rule
+func (r ScopeRule) Match(link string) bool {
+	host, rest, ok := splitLink(link)
+	if !ok {
+		return false
+	}
+
+	host = siteHost(host)
+	if host != strings.TrimPrefix(r.Host, "*.") && !get.MatchHost(host, r.Host) {
+		return false
+	}
+
+	// The path matches whole segments, so /docs doesn't match /docs-private
+	after, found := strings.CutPrefix(rest, r.Path)
+	return found && (after == "" || strings.ContainsRune("/?#", rune(after[0])))
+}
+
+// InScope checks if the link matches any of the scope rules
+func (s *Scraper) InScope(link string) bool {
+	for _, rule := range s.Scope {
+		if rule.Match(link) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hostFolder returns the folder the pages of the given host are saved on.
+// The ones of the host of the site are saved on the root.
+func (s *Scraper) hostFolder(host string) string {
+	host = siteHost(host)
+	if host == s.siteHost {
+		return ""
+	}
+
+	return "/" + host
+}
+
+// siteHost returns the host in lower case and without www.
+func siteHost(host string) string {
+	return strings.TrimPrefix(strings.ToLower(host), "www.")
+}
+
+// splitLink splits an http or https link into its host, and the rest of it
+func splitLink(link string) (host, rest string, ok bool) {
+	scheme, after, found := strings.Cut(link, "://")
+	if !found || (!strings.EqualFold(scheme, "http") && !strings.EqualFold(scheme, "https")) {
+		return "", "", false
+	}
+
+	i := strings.IndexAny(after, "/?#")
+	if i < 0 {
+		return after, "", after != ""
+	}
+
+	return after[:i], after[i:], i > 0
+}
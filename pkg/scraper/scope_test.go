@@ -0,0 +1,129 @@
+package scraper_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScope(t *testing.T) {
+	for rule, expected := range map[string]scraper.ScopeRule{
+		"example.com":                   {Host: "example.com"},
+		"WWW.Example.com":               {Host: "example.com"},
+		"*.example.com":                 {Host: "*.example.com"},
+		"https://example.com/docs/":     {Host: "example.com", Path: "/docs"},
+		"http://*.example.com:8080/a/b": {Host: "*.example.com:8080", Path: "/a/b"},
+	} {
+		got, err := scraper.ParseScope(rule)
+		assert.NoError(t, err, rule)
+		assert.Equal(t, expected, got, rule)
+	}
+
+	for _, invalid := range []string{"", "*.", "https://", "ex*mple.com", "*.*.example.com"} {
+		_, err := scraper.ParseScope(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	scope, err := scraper.ParseScopes("example.com, ,*.example.org")
+	assert.NoError(t, err)
+	assert.Len(t, scope, 2)
+}
+
+func TestScopeMatch(t *testing.T) {
+	site, _ := scraper.ParseScope("example.com")
+	assert.True(t, site.Match("https://example.com/about/"))
+	assert.True(t, site.Match("http://www.example.com"))
+	assert.True(t, site.Match("HTTPS://WWW.EXAMPLE.COM/?q=1"))
+	assert.False(t, site.Match("https://blog.example.com/"))
+	assert.False(t, site.Match("https://example.com.evil.org/"))
+	assert.False(t, site.Match("ftp://example.com/"))
+
+	wildcard, _ := scraper.ParseScope("*.example.com")
+	assert.True(t, wildcard.Match("https://example.com/"))
+	assert.True(t, wildcard.Match("http://blog.example.com/post/"))
+	assert.True(t, wildcard.Match("https://a.b.example.com"))
+	assert.False(t, wildcard.Match("https://notexample.com/"))
+
+	docs, _ := scraper.ParseScope("https://example.com/docs")
+	assert.True(t, docs.Match("http://www.example.com/docs/intro/"))
+	assert.True(t, docs.Match("https://example.com/docs"))
+	assert.True(t, docs.Match("https://example.com/docs?q=1"))
+	assert.True(t, docs.Match("https://example.com/docs#intro"))
+	assert.False(t, docs.Match("https://example.com/blog/"))
+	assert.False(t, docs.Match("https://example.com/docs-private/"))
+	assert.False(t, docs.Match("https://example.com/docsfoo"))
+}
+
+func TestRemoveDomainScope(t *testing.T) {
+	s := initiate(t, &scraper.Config{OldDomain: "https://www.example.com", Scope: "*.example.com"})
+
+	assert.True(t, s.IsInternLink("http://example.com/about/"))
+	assert.True(t, s.IsInternLink("https://shop.example.com/"))
+	assert.False(t, s.IsInternLink("https://example.org/"))
+
+	assert.Equal(t, "/about/", s.RemoveDomain("https://www.example.com/about/"))
+	assert.Equal(t, "/about/", s.RemoveDomain("http://example.com/about/"))
+	assert.Equal(t, "/shop.example.com/cart/", s.RemoveDomain("https://Shop.example.com/cart/"))
+	assert.Equal(t, "/shop.example.com/?page=2", s.RemoveDomain("https://shop.example.com?page=2"))
+	assert.Equal(t, "https://example.org/", s.RemoveDomain("https://example.org/"))
+
+	// Without scope, only the roots are intern
+	s = initiate(t, &scraper.Config{OldDomain: "https://www.example.com"})
+	assert.False(t, s.IsInternLink("http://example.com/about/"))
+	assert.False(t, s.IsInternLink("https://shop.example.com/"))
+}
+
+func TestScope(t *testing.T) {
+	getter := site(t, map[string]string{
+		"https://example.com": `<html><body>` +
+			`<a href="http://www.example.com/about/">About</a>` +
+			`<a href="https://blog.example.com/">Blog</a>` +
+			`<a href="https://other.com/">Other</a>` +
+			`</body></html>`,
+		"http://www.example.com/about/":     `<html><body><a href="https://example.com/">Home</a></body></html>`,
+		"https://blog.example.com/":         `<html><body><a href="/first/">First</a><img src="/logo.png"></body></html>`,
+		"https://blog.example.com/first/":   `<html><body><a href="/">Blog</a></body></html>`,
+		"https://blog.example.com/logo.png": `png`,
+	})
+
+	path := t.TempDir()
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(path), scraper.WithScope("*.example.com"))
+	assert.NoError(t, err)
+
+	result, err := s.Run(context.Background())
+	assert.NoError(t, err)
+
+	pages := append([]string{}, result.Pages...)
+	sort.Strings(pages)
+	assert.Equal(t, []string{
+		"http://www.example.com/about/",
+		"https://blog.example.com/",
+		"https://blog.example.com/first/",
+		"https://example.com",
+	}, pages)
+	assert.Equal(t, []string{"https://blog.example.com/logo.png"}, result.Files)
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(path, filepath.FromSlash(name)))
+		assert.NoError(t, err, name)
+		return string(data)
+	}
+
+	home := read("index.html")
+	assert.Contains(t, home, `href="/about/"`)
+	assert.Contains(t, home, `href="/blog.example.com/"`)
+	assert.Contains(t, home, `href="https://other.com/"`)
+
+	blog := read("blog.example.com/index.html")
+	assert.Contains(t, blog, `href="/blog.example.com/first/"`)
+	assert.Contains(t, blog, `src="/blog.example.com/logo.png"`)
+
+	assert.Contains(t, read("about/index.html"), `href="/"`)
+	assert.Contains(t, read("blog.example.com/first/index.html"), `href="/blog.example.com/"`)
+	assert.Equal(t, "png", read("blog.example.com/logo.png"))
+}
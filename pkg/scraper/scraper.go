@@ -1,6 +1,8 @@
 package scraper
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -8,38 +10,218 @@ import (
 	"golang.org/x/net/html"
 )
 
-// Run runs the scraper
-func (s *Scraper) Run() {
-	defer s.Close()
+// Run runs the scraper until the whole site is crawled, or the context is
+// done. The domain is checked first, failing if it can't be got. The pages
+// already fetched are saved when the context is done, and the result is
+// returned along with the error of the context.
+func (s *Scraper) Run(ctx context.Context) (Result, error) {
+	if ctx.Err() != nil {
+		s.Stop()
+	}
+	stop := context.AfterFunc(ctx, s.Stop)
+	s.ctx = ctx
+
+	if err := s.checkDomain(); err != nil {
+		stop()
+		s.Close()
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return s.Result(), err
+	}
+
+	s.run()
+	stop()
+
+	s.emit(Event{Type: RunFinished})
+	s.Close()
+
+	return s.Result(), ctx.Err()
+}
+
+// checkDomain gets the domain, following its redirects, and makes the URL
+// they end on the root of the site
+func (s *Scraper) checkDomain() error {
+	s.status("Checking domain")
+
+	resp, err := follow(s.context(), s.Get, s.OldDomain, nil)
+	if err != nil {
+		return fmt.Errorf("error getting domain: %s", err)
+	}
+
+	if len(resp.Redirects) > 0 {
+		s.Redirects[s.OldDomain] = resp.Redirects
+		for _, hop := range resp.Redirects {
+			s.emit(Event{Type: Redirected, URL: hop.From, Target: hop.To, Status: hop.Status})
+		}
+		s.status(fmt.Sprintf("Redirected to %s", resp.URL))
+	}
+
+	if resp.Status != http.StatusOK {
+		return fmt.Errorf("status code error: %d on %s", resp.Status, s.OldDomain)
+	}
+
+	s.setRoot(RemoveLastSlash(s.Normalizer.Normalize(resp.URL)))
+
+	s.emit(Event{Type: RunStarted, URL: s.Roots[0]})
+	s.status("Initiating scraper")
+
+	return nil
+}
+
+// setRoot makes the given URL the root of the site, in place of the domain,
+// on the roots and the scope
+func (s *Scraper) setRoot(root string) {
+	if root == s.Roots[0] {
+		return
+	}
+
+	// The scope starts with the site, if there is any
+	if site, err := ParseScope(s.Roots[0]); err == nil && len(s.Scope) > 0 && s.Scope[0] == site {
+		s.Scope = s.Scope[1:]
+		if site, err := ParseScope(root); err == nil {
+			s.Scope = append([]ScopeRule{site}, s.Scope...)
+		}
+	}
+
+	s.Roots[0] = root
+	host, _, _ := splitLink(root)
+	s.siteHost = siteHost(host)
+}
+
+// run crawls the site, and saves or checks everything found
+func (s *Scraper) run() {
 	s.Scrape()
+
+	// Only check the links, without saving anything
+	if s.Check {
+		s.CheckAttachments()
+		if s.CheckExternal {
+			s.CheckExternalLinks()
+		}
+
+		err := s.ExportCheck()
+		if err != nil {
+			s.fail("", err)
+		}
+		return
+	}
+
 	s.DownloadAttachments()
+
+	// Wait for the pages still being saved
+	s.saving.Wait()
+
+	if s.RedirectStubs {
+		s.SaveRedirects()
+	}
+
+	if s.UseCanonical {
+		s.SaveDuplicates()
+
+		err := s.ExportDuplicates()
+		if err != nil {
+			s.fail("", err)
+		}
+	}
+
+	if s.Sanitize {
+		err := s.ExportSanitized()
+		if err != nil {
+			s.fail("", err)
+		}
+	}
+
+	if s.RedirectsFormat != "" {
+		err := s.ExportRedirects(s.RedirectsFormat)
+		if err != nil {
+			s.fail("", err)
+		}
+	}
+
+	if s.Incremental {
+		err := s.SaveChanges()
+		if err != nil {
+			s.fail("", err)
+		}
+	}
+
+	err := s.SaveManifest()
+	if err != nil {
+		s.fail("", err)
+	}
 }
 
 // getLinks Get the links from a HTML site
 func (s *Scraper) getLinks(domain string) (page Page, attachments []string, err error) {
-	got, status, buf, err := s.Get.Get(domain)
+	resp, elapsed, err := s.fetch(domain, s.conditionalHeader)
+	s.checked(domain, resp.Status, err, false)
+
+	event := s.fetched(PageFetched, domain, resp, elapsed)
+	defer func() {
+		event.Err = err
+		s.emit(event)
+	}()
+
 	if err != nil {
-		s.Con.AddErrors(err.Error())
 		return
 	}
 
 	// If rediection, get the new domain
-	if status == http.StatusMovedPermanently || status == http.StatusFound {
-		domain = got
-	} else if status != http.StatusOK {
-		return page, attachments, fmt.Errorf("status code error: %d on %s", status, domain)
+	if len(resp.Redirects) > 0 {
+		domain = resp.URL
+		page.Redirects = resp.Redirects
 	}
 
-	page.HTML = buf.String()
+	page.ETag = resp.Header.Get("ETag")
+	page.LastModified = resp.Header.Get("Last-Modified")
+
+	// Not modified since the previous run: take its links from the manifest
+	if resp.Status == http.StatusNotModified {
+		if previous, ok := s.Previous[domain]; ok {
+			event.Path = previous.Path
+			page.URL = domain
+			page.NotModified = true
+			for _, link := range previous.Links {
+				page.Links = append(page.Links, Links{Href: link})
+			}
+			return page, previous.Attachments, nil
+		}
+	}
+
+	if resp.Status != http.StatusOK {
+		return page, attachments, fmt.Errorf("status code error: %d on %s", resp.Status, domain)
+	}
 
-	doc, err := html.Parse(buf)
+	if !s.Check {
+		event.Path = relativePath(s.PreparePathsPage(domain))
+	}
+
+	page.HTML = resp.Body.String()
+
+	doc, err := html.Parse(resp.Body)
 	if err != nil {
-		s.Con.AddErrors(err.Error())
 		return
 	}
 
 	page.URL = domain
 
+	// Remove what makes the page request URLs outside the roots
+	if s.Sanitize {
+		page.Removed, page.Flagged = s.sanitize(domain, doc)
+		if len(page.Removed) > 0 {
+			buf := new(bytes.Buffer)
+			if err = html.Render(buf, doc); err != nil {
+				return
+			}
+			page.HTML = buf.String()
+		}
+	}
+
+	if s.CheckExternal {
+		page.External = s.externalLinks(domain, doc)
+	}
+
 	var f func(*html.Node)
 	f = func(n *html.Node) {
 		for _, a := range n.Attr {
@@ -61,6 +243,11 @@ func (s *Scraper) getLinks(domain string) (page Page, attachments []string, err
 						continue
 					}
 
+					if asset, ok := s.addExternal(found, ""); ok {
+						attachments = append(attachments, asset)
+						continue
+					}
+
 					foundLink := s.SanitizeURL(found)
 					if s.IsValidAttachment(foundLink) {
 						attachments = append(attachments, foundLink)
@@ -69,11 +256,29 @@ func (s *Scraper) getLinks(domain string) (page Page, attachments []string, err
 			}
 		}
 
+		// Get the canonical URL
+		if n.Type == html.ElementNode && n.Data == "link" && HasRel(n, "canonical") {
+			for _, a := range n.Attr {
+				if a.Key == "href" {
+					link, err := s.Get.ParseURL(domain, a.Val)
+					if err == nil {
+						page.Canonical = s.SanitizeURL(link)
+					}
+				}
+			}
+		}
+
 		// Get CSS Links
 		if n.Type == html.ElementNode && n.Data == "link" {
 			for _, a := range n.Attr {
 				if a.Key == "href" {
 					link, err := s.Get.ParseURL(domain, a.Val)
+					if err == nil && isRequisiteLink(n) {
+						if asset, ok := s.addExternal(link, requisiteExt(n)); ok {
+							attachments = append(attachments, asset)
+							continue
+						}
+					}
 					if err == nil {
 						foundLink := s.SanitizeURL(link)
 						if s.IsValidAttachment(foundLink) {
@@ -93,6 +298,12 @@ func (s *Scraper) getLinks(domain string) (page Page, attachments []string, err
 					for _, a := range c.Attr {
 						if a.Key == "href" {
 							link, err := s.Get.ParseURL(domain, a.Val)
+							if err == nil && isRequisiteLink(c) {
+								if asset, ok := s.addExternal(link, requisiteExt(c)); ok {
+									attachments = append(attachments, asset)
+									continue
+								}
+							}
 							if err == nil {
 								foundLink := s.SanitizeURL(link)
 								if s.IsValidAttachment(foundLink) {
@@ -106,6 +317,12 @@ func (s *Scraper) getLinks(domain string) (page Page, attachments []string, err
 					for _, a := range c.Attr {
 						if a.Key == "src" {
 							link, err := s.Get.ParseURL(domain, a.Val)
+							if err == nil {
+								if asset, ok := s.addExternal(link, ".js"); ok {
+									attachments = append(attachments, asset)
+									continue
+								}
+							}
 							if err == nil {
 								foundLink := s.SanitizeURL(link)
 								if s.IsValidAttachment(foundLink) {
@@ -123,6 +340,12 @@ func (s *Scraper) getLinks(domain string) (page Page, attachments []string, err
 			for _, a := range n.Attr {
 				if a.Key == "src" {
 					link, err := s.Get.ParseURL(domain, a.Val)
+					if err == nil {
+						if asset, ok := s.addExternal(link, ".js"); ok {
+							attachments = append(attachments, asset)
+							continue
+						}
+					}
 					if err == nil {
 						foundLink := s.SanitizeURL(link)
 						if s.IsValidAttachment(foundLink) {
@@ -138,6 +361,12 @@ func (s *Scraper) getLinks(domain string) (page Page, attachments []string, err
 			for _, a := range n.Attr {
 				if a.Key == "src" {
 					link, err := s.Get.ParseURL(domain, a.Val)
+					if err == nil {
+						if asset, ok := s.addExternal(link, ""); ok {
+							attachments = append(attachments, asset)
+							continue
+						}
+					}
 					if err == nil {
 						foundLink := s.SanitizeURL(link)
 						if s.IsValidAttachment(foundLink) {
@@ -160,6 +389,28 @@ func (s *Scraper) getLinks(domain string) (page Page, attachments []string, err
 			}
 		}
 
+		// Follow meta refresh redirections as links
+		if n.Type == html.ElementNode && n.Data == "meta" && isMetaRefresh(n) {
+			for _, a := range n.Attr {
+				if a.Key == "content" {
+					target := ParseMetaRefresh(a.Val)
+					if target == "" {
+						continue
+					}
+					link, err := s.Get.ParseURL(domain, target)
+					if err == nil {
+						foundLink := s.SanitizeURL(link)
+						newLink := Links{Href: foundLink}
+						if s.IsValidSite(foundLink) && !s.DoesLinkExist(newLink, page.Links) {
+							page.Links = append(page.Links, newLink)
+						} else if s.IsValidAttachment(foundLink) {
+							attachments = append(attachments, foundLink)
+						}
+					}
+				}
+			}
+		}
+
 		// Get links
 		if n.Type == html.ElementNode && n.Data == "a" {
 			ok := false
@@ -194,73 +445,128 @@ func (s *Scraper) getLinks(domain string) (page Page, attachments []string, err
 	return
 }
 
-// TakeLinks take links from the given site
-func (s *Scraper) TakeLinks(link string) {
+// TakeLinks takes the links and attachments from the given site. The error
+// is notified too, unless the scraper was stopped before fetching it.
+func (s *Scraper) TakeLinks(link string) (page Page, err error) {
 
-	s.Started <- 1
-	s.Scanning <- 1
+	origin := s.origin(link)
 
-	s.Con.AddStarted()
-	s.Con.AddStatus("Scraping " + link)
+	s.emit(Event{Type: PageQueued, URL: link, Referrer: origin.Referrer, Depth: origin.Depth})
 
+	s.scanning <- struct{}{}
 	defer func() {
-		<-s.Scanning
-		s.Finished <- 1
-		s.Con.AddFinished()
+		<-s.scanning
 	}()
 
+	s.status("Scraping " + link)
+
+	// Stopped: finish without fetching the page
+	if s.Stopped() {
+		s.emit(Event{Type: PageFetched, URL: link, Referrer: origin.Referrer, Depth: origin.Depth, Err: ErrStopped})
+		return page, ErrStopped
+	}
+
 	// Get links
 	page, attached, err := s.getLinks(link)
 	if err != nil {
-		s.Con.AddErrors(err.Error())
-	} else {
-		s.Pages <- page
-		s.Attachments <- attached
-		s.NewLinks <- page.Links
+		s.fail(link, err)
+		return
+	}
+
+	depth := origin.Depth
+	for _, found := range page.Links {
+		s.setOrigin(found.Href, page.URL, depth+1)
+	}
+	for _, found := range attached {
+		s.setOrigin(found, page.URL, depth+1)
+	}
+	for _, found := range page.External {
+		s.setOrigin(found, page.URL, depth+1)
 	}
+
+	page.Attachments = attached
+	return page, nil
+}
+
+// taken is a page taken by TakeLinks, or the error taking it
+type taken struct {
+	page Page
+	err  error
 }
 
 // Scrape scrapes the site
 func (s *Scraper) Scrape() {
 
-	s.Con.AddStatus("Scraping " + s.OldDomain)
+	s.status("Scraping " + s.OldDomain)
+
+	// Every page is taken on its own goroutine, which always sends back
+	// a single result: the crawl is over once none is pending
+	results := make(chan taken)
+	take := func(link string) {
+		go func() {
+			page, err := s.TakeLinks(link)
+			results <- taken{page: page, err: err}
+		}()
+	}
 
 	// Take the links from the startsite
 	seen := make(map[string]bool)
-	s.TakeLinks(s.OldDomain)
 	seen[s.OldDomain] = true
+	take(s.OldDomain)
 
-	for {
-		select {
-		case links := <-s.NewLinks:
-			for _, link := range links {
-				if !seen[link.Href] {
-					seen[link.Href] = true
-					go s.TakeLinks(link.Href)
-				}
-			}
-		case page := <-s.Pages:
-			if !s.IsURLInSlice(page.URL, s.Indexed) {
-				s.Indexed = append(s.Indexed, page.URL)
+	for pending := 1; pending > 0; pending-- {
+		result := <-results
+		if result.err != nil {
+			continue
+		}
+
+		page := result.page
+		if len(page.Redirects) > 0 {
+			s.Redirects[page.Redirects[0].From] = page.Redirects
+		}
+		// Pages with a canonical URL on the site are saved only once, on it
+		saveAs := page.URL
+		if s.UseCanonical && page.Canonical != "" && s.IsInternLink(page.Canonical) {
+			saveAs = page.Canonical
+			s.Duplicates[saveAs] = append(s.Duplicates[saveAs], page.URL)
+		}
+		s.addReferrers(page)
+		if len(page.Removed) > 0 || len(page.Flagged) > 0 {
+			s.Sanitized[page.URL] = SanitizedPage{Page: page.URL, Removed: page.Removed, Flagged: page.Flagged}
+		}
+		if !s.IsURLInSlice(saveAs, s.Indexed) {
+			s.Indexed = append(s.Indexed, saveAs)
+			switch {
+			case s.Check:
+				// Nothing is saved when checking the links
+			case page.NotModified:
+				s.keepFile(page.URL)
+			default:
+				s.saving.Add(1)
 				go func() {
-					err := s.SaveHTML(page.URL, page.HTML)
+					defer s.saving.Done()
+					err := s.SavePage(saveAs, page)
 					if err != nil {
-						s.Con.AddErrors(err.Error())
+						s.fail(saveAs, err)
 					}
 				}()
 			}
-		case attachment := <-s.Attachments:
-			for _, link := range attachment {
-				if !s.IsURLInSlice(link, s.Files) {
-					s.Con.AddAttachments()
-					s.Files = append(s.Files, link)
-				}
+		}
+
+		for _, link := range page.Attachments {
+			if !s.IsURLInSlice(link, s.Files) {
+				origin := s.origin(link)
+				s.emit(Event{Type: AssetQueued, URL: link, Referrer: origin.Referrer, Depth: origin.Depth})
+				s.Files = append(s.Files, link)
 			}
 		}
 
-		// Break the for loop once all scans are finished
-		if len(s.Started) > 1 && len(s.Scanning) == 0 && len(s.Started) == len(s.Finished) {
-			break
+		for _, link := range page.Links {
+			if !seen[link.Href] {
+				seen[link.Href] = true
+				pending++
+				take(link.Href)
+			}
 		}
 	}
 }
@@ -269,35 +575,37 @@ func (s *Scraper) Scrape() {
 func (s *Scraper) DownloadAttachments() {
 	for _, attachedFile := range s.Files {
 		attachedFile := attachedFile
+		if s.Stopped() {
+			break
+		}
 
 		// First, seek for more attachments on the CSS and JS files
-		if strings.Contains(attachedFile, ".css") || strings.Contains(attachedFile, ".js") {
+		if strings.Contains(attachedFile, ".css") || strings.Contains(attachedFile, ".js") || s.isExternal(attachedFile) {
 			moreAttachments, err := s.GetInsideAttachments(attachedFile)
 			if err != nil {
-				s.Con.AddErrors(err.Error())
+				s.fail(attachedFile, err)
 				continue
 			}
 			for _, link := range moreAttachments {
 				link := link
+				depth := s.origin(attachedFile).Depth + 1
+				s.setOrigin(link, attachedFile, depth)
 				if !s.IsURLInSlice(link, s.Files) {
 					s.Files = append(s.Files, link)
-					s.Con.AddDownloading()
+					s.emit(Event{Type: AssetQueued, URL: link, Referrer: attachedFile, Depth: depth})
 
 					err := s.SaveAttachment(link)
 					if err != nil {
-						s.Con.AddErrors(err.Error())
+						s.fail(link, err)
 					}
-					s.Con.AddDownloaded()
 				}
 			}
 		}
 
 		err := s.SaveAttachment(attachedFile)
 		if err != nil {
-			s.Con.AddErrors(err.Error())
+			s.fail(attachedFile, err)
 		}
-
-		s.Con.AddDownloaded()
 	}
 
 }
@@ -0,0 +1,174 @@
+package scraper_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// site returns a getter serving the given pages, with a 404 for any other URL
+func site(t *testing.T, pages map[string]string) *get.MockHttpGet {
+	ctrl := gomock.NewController(t)
+
+	mockHttpGet := get.NewMockHttpGet(ctrl)
+	mockHttpGet.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(link string, header http.Header) (string, int, *bytes.Buffer, http.Header, error) {
+		body, ok := pages[link]
+		if !ok {
+			return link, http.StatusNotFound, bytes.NewBufferString(""), nil, nil
+		}
+		return link, http.StatusOK, bytes.NewBufferString(body), http.Header{"Content-Type": []string{"text/html"}}, nil
+	}).AnyTimes()
+	mockHttpGet.EXPECT().ParseURL(gomock.Any(), gomock.Any()).DoAndReturn(get.New().ParseURL).AnyTimes()
+
+	return mockHttpGet
+}
+
+func TestRun(t *testing.T) {
+	getter := site(t, map[string]string{
+		"https://example.com":             `<html><body><a href="/about/">About</a><a href="/blog/">Blog</a><img src="/logo.png"></body></html>`,
+		"https://example.com/about/":      `<html><body><a href="/contact/">Contact</a></body></html>`,
+		"https://example.com/blog/":       `<html><body><a href="/blog/first/">First</a></body></html>`,
+		"https://example.com/blog/first/": `<html><body><a href="/">Home</a></body></html>`,
+		"https://example.com/":            `<html><body></body></html>`,
+		"https://example.com/logo.png":    `png`,
+	})
+
+	path := t.TempDir()
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(path), scraper.WithConnections(2))
+	assert.NoError(t, err)
+
+	result, err := s.Run(context.Background())
+	assert.NoError(t, err)
+
+	sort.Strings(result.Pages)
+	assert.Equal(t, []string{
+		"https://example.com",
+		"https://example.com/about/",
+		"https://example.com/blog/",
+		"https://example.com/blog/first/",
+	}, result.Pages)
+	assert.Equal(t, []string{"https://example.com/logo.png"}, result.Files)
+
+	// The missing page is on the errors, by its URL
+	assert.Len(t, result.Errors, 1)
+	var urlErr *scraper.URLError
+	assert.True(t, errors.As(result.Errors[0], &urlErr))
+	assert.Equal(t, "https://example.com/contact/", urlErr.URL)
+
+	// The home page is fetched with and without its trailing slash
+	assert.Equal(t, 5, result.Stats.Pages)
+	assert.Equal(t, 1, result.Stats.Files)
+	assert.Equal(t, 1, result.Stats.Errors)
+	assert.Nil(t, result.Broken)
+
+	_, err = os.Stat(filepath.Join(path, "blog", "first", "index.html"))
+	assert.NoError(t, err)
+}
+
+func TestRunCheck(t *testing.T) {
+	getter := site(t, map[string]string{
+		"https://example.com": `<html><body><a href="/gone/">Gone</a></body></html>`,
+	})
+
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithCheck(false))
+	assert.NoError(t, err)
+
+	result, err := s.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, result.Broken, 1)
+	assert.Equal(t, "https://example.com", result.Broken[0].Page)
+}
+
+func TestRunCanceled(t *testing.T) {
+	getter := site(t, map[string]string{
+		"https://example.com": `<html><body><a href="/about/">About</a></body></html>`,
+	})
+
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(t.TempDir()))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := s.Run(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, result.Pages)
+}
+
+func TestRunCanceledDomain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	// Creating the scraper sends no request, the run is canceled while the
+	// domain is checked
+	s, err := scraper.New(server.URL, scraper.WithDownloadPath(t.TempDir()))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = s.Run(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := scraper.New("")
+	assert.Error(t, err)
+
+	_, err = scraper.New("https://example.com", scraper.WithConnections(0))
+	assert.Error(t, err)
+}
+
+func TestRunTransformers(t *testing.T) {
+	getter := site(t, map[string]string{
+		"https://example.com":          `<html><body><a href="/about/">About</a><img src="/logo.png"></body></html>`,
+		"https://example.com/about/":   `<html><body>About us</body></html>`,
+		"https://example.com/logo.png": `png`,
+	})
+
+	var mutex sync.Mutex
+	var seen []string
+	// Leave the links as they are, so the files are saved on the same paths
+	mark := scraper.TransformerFunc(func(c *scraper.Content) error {
+		mutex.Lock()
+		seen = append(seen, c.URL)
+		mutex.Unlock()
+
+		body, err := c.Bytes()
+		c.SetBytes(append(body, "<!-- mirrored -->"...))
+		return err
+	})
+
+	path := t.TempDir()
+	s, err := scraper.New("https://example.com", scraper.WithGetter(getter), scraper.WithDownloadPath(path), scraper.WithTransformers(mark))
+	assert.NoError(t, err)
+
+	_, err = s.Run(context.Background())
+	assert.NoError(t, err)
+
+	sort.Strings(seen)
+	assert.Equal(t, []string{"https://example.com", "https://example.com/about/", "https://example.com/logo.png"}, seen)
+
+	about, err := os.ReadFile(filepath.Join(path, "about", "index.html"))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><body>About us</body></html><!-- mirrored -->", string(about))
+
+	logo, err := os.ReadFile(filepath.Join(path, "logo.png"))
+	assert.NoError(t, err)
+	assert.Equal(t, "png<!-- mirrored -->", string(logo))
+}
@@ -4,21 +4,28 @@ import (
 	"errors"
 	"flag"
 	"fmt"
-	"net/http"
+	"net/url"
+	"os"
 	"strings"
 	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/get"
 )
 
 // Config holds the scraper configuration
 type Config struct {
 	// Original domain
-	OldDomain string `long:"u" short:"u"`
+	OldDomain string `flag:"u" config:"url"`
 
 	// New domain to rewrite the download HTML sites
-	NewDomain string `long:"new" short:"new"`
+	NewDomain string `flag:"new" config:"new-domain"`
 
 	// URL prefixes/roots that should be included in the scraper
-	IncludedURLs string `long:"r" short:"r"`
+	IncludedURLs string `flag:"r" config:"include" list:"true"`
+
+	// Scope rules of the hosts to crawl too, comma separated, like
+	// *.example.com. Each host is saved on its own folder.
+	Scope string `flag:"scope" config:"scope" list:"true"`
 
 	// Roots contains a range of URLs that can be considered the root
 	// This is useful for scraping sites where content is hosted on a CDN
@@ -26,84 +33,305 @@ type Config struct {
 	Roots []string
 
 	// Path where to save the downloads
-	DownloadPath string `long:"path" short:"path"`
+	DownloadPath string `flag:"path" config:"path"`
+
+	// Map names to ones that are safe on Windows and macOS too
+	PortablePaths bool `flag:"portable" config:"portable"`
 
 	// Use args on URLs
-	UseQueries bool `long:"q" short:"q"`
+	UseQueries bool `flag:"q" config:"queries"`
+
+	// Query params to strip from the URLs, comma separated. A trailing * matches any param with that prefix
+	StripParams string `flag:"strip-params" config:"strip-params" list:"true"`
+
+	// Query params to keep on the URLs, comma separated. If set, any other param is stripped
+	KeepParams string `flag:"keep-params" config:"keep-params" list:"true"`
+
+	// Save pages only once per canonical URL
+	UseCanonical bool `flag:"canonical" config:"canonical"`
 
 	// Number of concurrent queries
-	Simultaneous int `long:"s" short:"s"`
+	Simultaneous int `flag:"s" config:"connections"`
+
+	// Save stub pages on the old paths of redirected pages
+	RedirectStubs bool `flag:"stubs" config:"stubs"`
+
+	// Re-crawl with conditional requests, based on the previous run
+	Incremental bool `flag:"incremental" config:"incremental"`
+
+	// Format to export the redirects found, for static hosting platforms
+	RedirectsFormat string `flag:"redirects" config:"redirects"`
+
+	// Download the page requisites on external hosts too, except the ones of
+	// the denied hosts, or only the ones of the allowed hosts. Comma
+	// separated, a *. prefix matches any subdomain.
+	External      bool   `flag:"external" config:"external"`
+	ExternalAllow string `flag:"external-allow" config:"external-allow" list:"true"`
+	ExternalDeny  string `flag:"external-deny" config:"external-deny" list:"true"`
+
+	// Remove external scripts, iframes, tracking pixels, resource hints and
+	// event handlers from the pages
+	Sanitize bool `flag:"sanitize" config:"sanitize"`
+
+	// Rules file of the transformers of the pages and files saved
+	Transform string `flag:"transform" config:"transform"`
+
+	// Check the links of the site instead of saving it
+	Check bool `flag:"check" config:"check"`
+
+	// Check the external links too, with HEAD requests
+	CheckExternal bool `flag:"check-external" config:"check-external"`
+
+	// Format of the broken links report
+	CheckFormat string `flag:"check-format" config:"check-format"`
+
+	// File to write the broken links report to, instead of the standard output
+	CheckOutput string `flag:"check-output" config:"check-output"`
+
+	// File to write the crawl log to, with a line per URL fetched
+	CrawlLog string `flag:"log" config:"log"`
+
+	// Format of the crawl log
+	CrawlLogFormat string `flag:"log-format" config:"log-format"`
+
+	// Console to show the progress on: auto, tui, tty, plain, json or quiet
+	Console string `flag:"console" config:"console"`
+
+	// Minimum level of the lines printed by the plain console
+	ConsoleLevel string `flag:"level" config:"level"`
+
+	// Times to retry the requests that failed, or got a 429 or 5xx status
+	Retries int `flag:"retries" config:"retries"`
+
+	// Maximum number of requests per second, 0 for no limit
+	Rate float64 `flag:"rate" config:"rate"`
+
+	// User and password, as user:password, or token to authenticate the
+	// requests to the site with
+	Auth  string `flag:"auth" config:"auth"`
+	Token string `flag:"token" config:"token"`
+
+	// Address to serve the Prometheus metrics on, like :9090
+	Metrics string `flag:"metrics" config:"metrics"`
+
+	// Config file the config was loaded from, and profile of it used
+	ConfigFile string `flag:"config"`
+	Profile    string `flag:"profile"`
 }
 
 // validateFlags ensures all required flags are set and values are valid
 func validateFlags(conf *Config) error {
 	if conf.OldDomain == "" {
-		return errors.New("missing required flag: -u (URL)")
+		return errors.New("missing required flag: -u (URL), or url on the config file")
+	}
+
+	if _, err := ParseScopes(conf.Scope); err != nil {
+		return fmt.Errorf("invalid scope: -scope (%s)", err)
 	}
 
 	if conf.Simultaneous <= 0 {
 		return errors.New("invalid number of connections: -s (must be at least 1)")
 	}
 
+	if conf.RedirectsFormat != "" && !IsInSlice(conf.RedirectsFormat, RedirectsFormats) {
+		return fmt.Errorf("invalid redirects format: -redirects (must be one of %s)", strings.Join(RedirectsFormats, ", "))
+	}
+
+	if conf.Retries < 0 {
+		return errors.New("invalid number of retries: -retries (must be at least 0)")
+	}
+
+	if conf.Rate < 0 {
+		return errors.New("invalid rate: -rate (must be at least 0)")
+	}
+
+	if conf.Auth != "" && !strings.Contains(conf.Auth, ":") {
+		return errors.New("invalid auth: -auth (must be user:password)")
+	}
+
+	if conf.CheckFormat != "" && !IsInSlice(conf.CheckFormat, CheckFormats) {
+		return fmt.Errorf("invalid check format: -check-format (must be one of %s)", strings.Join(CheckFormats, ", "))
+	}
+
+	if conf.CrawlLogFormat != "" && !IsInSlice(conf.CrawlLogFormat, CrawlLogFormats) {
+		return fmt.Errorf("invalid crawl log format: -log-format (must be one of %s)", strings.Join(CrawlLogFormats, ", "))
+	}
+
 	return nil
 }
 
-// parseFlags parses command line arguments and validates them
-func ParseFlags() (*Config, error) {
-	conf := &Config{
+// Backoff before the first retry of a request, doubled before the next ones
+var RetryBackoff = time.Second
+
+// Middlewares returns the middlewares of the fetches for the config: the
+// retries, the rate limit and the authentication. The credentials are only
+// sent to the hosts of the site and its roots.
+func (conf *Config) Middlewares() (middlewares []get.Middleware) {
+	if conf.Retries > 0 {
+		middlewares = append(middlewares, get.Retry(conf.Retries, RetryBackoff))
+	}
+
+	if conf.Rate > 0 {
+		middlewares = append(middlewares, get.RateLimit(conf.Rate))
+	}
+
+	if conf.Auth == "" && conf.Token == "" {
+		return
+	}
+
+	var hosts []string
+	for _, root := range append([]string{conf.OldDomain}, strings.Split(conf.IncludedURLs, ",")...) {
+		if u, err := url.Parse(strings.TrimSpace(root)); err == nil && u.Host != "" {
+			hosts = append(hosts, u.Host)
+		}
+	}
+
+	if conf.Auth != "" {
+		user, password, _ := strings.Cut(conf.Auth, ":")
+		middlewares = append(middlewares, get.BasicAuth(user, password, hosts...))
+	}
+
+	if conf.Token != "" {
+		middlewares = append(middlewares, get.BearerToken(conf.Token, hosts...))
+	}
+
+	return
+}
+
+// DefaultConfig returns the config used for the values not set on the
+// command line, the environment or the config file
+func DefaultConfig() *Config {
+	return &Config{
 		Simultaneous: 3, // Set default value
 		DownloadPath: "./website",
 		UseQueries:   false,
+		StripParams:  strings.Join(DefaultStripParams, ","),
+		CheckFormat:  "text",
+
+		CrawlLogFormat: "jsonl",
+
+		Console:      "auto",
+		ConsoleLevel: "info",
 	}
-	flag.StringVar(&conf.OldDomain, "u", "", "URL to download content from. (required)")
-	flag.StringVar(&conf.NewDomain, "new", "", "New URL to use for downloaded content (optional)")
-	flag.StringVar(&conf.IncludedURLs, "r", "", "URL prefixes/root paths that should be included (optional)")
-	flag.IntVar(&conf.Simultaneous, "s", conf.Simultaneous, "Number of concurrent connections (default: 3, minimum: 1)")
-	flag.BoolVar(&conf.UseQueries, "q", conf.UseQueries, "Ignore query strings in URLs (optional)")
-	flag.StringVar(&conf.DownloadPath, "path", conf.DownloadPath, "Local path to save downloaded files (default: ./website)")
+}
+
+// Usage of the commands that crawl a site
+var commandUsages = map[string]string{
+	"crawl": "Usage: ./go-download-web crawl [options] -u <URL>\nDownloads website content and saves it locally.",
+	"check": "Usage: ./go-download-web check [options] -u <URL>\nChecks the links of a site for broken ones, without saving anything.",
+}
 
-	help := flag.Bool("h", false, "Show this help message")
+// ParseArgs parses the arguments of the given command, crawl or check, and
+// validates them. Every value is taken from the flags, then the environment
+// variables, then the config file, and then the defaults. It returns
+// flag.ErrHelp if the help was requested.
+func ParseArgs(command string, args []string) (*Config, error) {
+	usage, ok := commandUsages[command]
+	if !ok {
+		return nil, fmt.Errorf("unknown command: %s", command)
+	}
 
-	flag.Parse()
+	conf := DefaultConfig()
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
 
-	if *help {
-		PrintUsage()
-		return nil, errors.New("help requested")
+	flags.StringVar(&conf.OldDomain, "u", "", "URL to download content from. (required)")
+	flags.StringVar(&conf.IncludedURLs, "r", "", "URL prefixes/root paths that should be included (optional)")
+	flags.StringVar(&conf.Scope, "scope", "", "Hosts to crawl too, comma separated, each saved on its own folder: any scheme, www or not, *. matching any subdomain (optional)")
+	flags.IntVar(&conf.Simultaneous, "s", conf.Simultaneous, "Number of concurrent connections (default: 3, minimum: 1)")
+	flags.BoolVar(&conf.UseQueries, "q", conf.UseQueries, "Ignore query strings in URLs (optional)")
+	flags.StringVar(&conf.StripParams, "strip-params", conf.StripParams, "Query params to strip from URLs, comma separated, * as suffix wildcard (optional)")
+	flags.StringVar(&conf.KeepParams, "keep-params", conf.KeepParams, "Query params to keep on URLs, comma separated, stripping any other (optional, implies -q)")
+
+	if command == "crawl" {
+		flags.StringVar(&conf.NewDomain, "new", "", "New URL to use for downloaded content (optional)")
+		flags.StringVar(&conf.DownloadPath, "path", conf.DownloadPath, "Local path to save downloaded files (default: ./website)")
+		flags.BoolVar(&conf.PortablePaths, "portable", conf.PortablePaths, "Save files with names that are safe on Windows and macOS too (optional)")
+		flags.BoolVar(&conf.UseCanonical, "canonical", conf.UseCanonical, "Save pages only once per canonical URL and report the duplicates (optional)")
+		flags.BoolVar(&conf.RedirectStubs, "stubs", conf.RedirectStubs, "Save pages on the old paths of redirected pages, redirecting to the new ones (optional)")
+		flags.BoolVar(&conf.Incremental, "incremental", conf.Incremental, "Only download what changed since the previous run on the same path (optional)")
+		flags.StringVar(&conf.RedirectsFormat, "redirects", "", "Export the redirects found as netlify, nginx, apache, json or csv (optional)")
+		flags.BoolVar(&conf.External, "external", conf.External, "Download the page requisites on other hosts too, like CDNs, on the _external folder (optional)")
+		flags.StringVar(&conf.ExternalAllow, "external-allow", conf.ExternalAllow, "Hosts to download the page requisites of, comma separated, *. matching any subdomain (optional, implies -external)")
+		flags.StringVar(&conf.ExternalDeny, "external-deny", conf.ExternalDeny, "Hosts not to download the page requisites of, comma separated, *. matching any subdomain (optional)")
+		flags.BoolVar(&conf.Sanitize, "sanitize", conf.Sanitize, "Remove external scripts, iframes, tracking pixels, resource hints and event handlers from the pages (optional)")
+		flags.StringVar(&conf.Transform, "transform", conf.Transform, "YAML or JSON rules file to transform the pages and files with before saving them (optional)")
+		flags.BoolVar(&conf.Check, "check", conf.Check, "Check the links of the site for broken ones, without saving anything (optional, same as the check command)")
+	}
+
+	flags.BoolVar(&conf.CheckExternal, "check-external", conf.CheckExternal, "Check the external links too, with HEAD requests (optional, implies -check)")
+	flags.IntVar(&conf.Retries, "retries", conf.Retries, "Times to retry the requests that failed, or got a 429 or 5xx status (default: 0)")
+	flags.Float64Var(&conf.Rate, "rate", conf.Rate, "Maximum number of requests per second (default: 0, no limit)")
+	flags.StringVar(&conf.Auth, "auth", conf.Auth, "User and password to authenticate to the site with, as user:password (optional)")
+	flags.StringVar(&conf.Token, "token", conf.Token, "Bearer token to authenticate to the site with (optional)")
+	flags.StringVar(&conf.CheckFormat, "check-format", conf.CheckFormat, "Format of the broken links report: text, csv or junit (default: text)")
+	flags.StringVar(&conf.CheckOutput, "check-output", conf.CheckOutput, "File to write the broken links report to (default: standard output)")
+	flags.StringVar(&conf.CrawlLog, "log", conf.CrawlLog, "File to write a log line for every URL fetched to (optional)")
+	flags.StringVar(&conf.CrawlLogFormat, "log-format", conf.CrawlLogFormat, "Format of the crawl log: jsonl or csv (default: jsonl)")
+	flags.StringVar(&conf.Console, "console", conf.Console, "Console to show the progress on: auto, tui, tty, plain, json or quiet (default: auto, tui only on terminals)")
+	flags.StringVar(&conf.ConsoleLevel, "level", conf.ConsoleLevel, "Minimum level of the lines of the plain console: debug, info, warn or error (default: info)")
+	flags.StringVar(&conf.Metrics, "metrics", conf.Metrics, "Address to serve Prometheus metrics on /metrics, like :9090 (optional)")
+	flags.StringVar(&conf.ConfigFile, "config", os.Getenv(EnvPrefix+"CONFIG"), "YAML or TOML config file to load (optional, default: $"+EnvPrefix+"CONFIG)")
+	flags.StringVar(&conf.Profile, "profile", os.Getenv(EnvPrefix+"PROFILE"), "Profile of the config file to use (optional, default: $"+EnvPrefix+"PROFILE)")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	conf, err := ResolveConfig(flags, conf, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	if command == "check" {
+		conf.Check = true
 	}
 
 	if err := validateFlags(conf); err != nil {
-		PrintUsage()
+		flags.Usage()
 		return nil, err
 	}
 
 	return conf, nil
 }
 
-// PrintUsage prints the usage message
-func PrintUsage() {
-	fmt.Println("Usage: ./go-download-web [options] -u <URL>")
-	fmt.Println("Downloads website content and saves it locally.")
-	flag.PrintDefaults()
-}
-
-// New creates a new Scraper
-func New(conf *Config, getter HttpGet, con Console) (*Scraper, error) {
+// ResolveConfig returns the config with the values of the flags set on the
+// command line, then the ones of the environment, then the ones of the
+// config file, and then the defaults
+func ResolveConfig(flags *flag.FlagSet, parsed *Config, lookup func(string) (string, bool)) (*Config, error) {
+	conf := DefaultConfig()
+	conf.ConfigFile, conf.Profile = parsed.ConfigFile, parsed.Profile
 
-	con.AddStatus("Checking domain")
-
-	// Get the root domain
-	final, status, _, err := getter.Get(conf.OldDomain)
-	if err != nil {
-		return nil, fmt.Errorf("error getting domain: %s", err)
+	if conf.ConfigFile != "" {
+		if err := LoadConfigFile(conf.ConfigFile, conf.Profile, conf); err != nil {
+			return nil, err
+		}
+	} else if conf.Profile != "" {
+		return nil, errors.New("a profile needs a config file: -config")
 	}
 
-	if status == http.StatusMovedPermanently || status == http.StatusFound {
-		con.AddStatus(fmt.Sprintf("Redirected to %s", final))
-	} else if status != http.StatusOK {
-		return nil, fmt.Errorf("status code error: %d on %s", status, conf.OldDomain)
+	if err := ApplyEnv(conf, lookup); err != nil {
+		return nil, err
 	}
 
-	correct := RemoveLastSlash(final)
+	applyFlags(flags, parsed, conf)
+
+	return conf, nil
+}
+
+// NewFromConfig creates a new Scraper from the given config, notifying the
+// given observers of every event. The domain is not checked until it is run.
+func NewFromConfig(conf *Config, getter HttpGet, observers ...Observer) (*Scraper, error) {
+	normalizer := NewNormalizer(conf.StripParams, conf.KeepParams)
+	correct := RemoveLastSlash(normalizer.Normalize(conf.OldDomain))
 
 	// Prepare the roots
 	conf.Roots = append(conf.Roots, correct)
@@ -114,47 +342,94 @@ func New(conf *Config, getter HttpGet, con Console) (*Scraper, error) {
 				continue
 			}
 			url = strings.TrimSpace(url)
-			url = RemoveLastSlash(url)
+			url = RemoveLastSlash(normalizer.Normalize(url))
 			conf.Roots = append(conf.Roots, url)
 		}
 	}
 
-	con.AddDomain(correct)
+	// Prepare the scope, with the site on it
+	scope, err := ParseScopes(conf.Scope)
+	if err != nil {
+		return nil, err
+	}
+	if site, err := ParseScope(correct); err == nil && len(scope) > 0 {
+		scope = append([]ScopeRule{site}, scope...)
+	}
+	host, _, _ := splitLink(correct)
 
-	con.AddStatus("Initiating scraper")
+	previous := make(map[string]ManifestEntry)
+	if conf.Incremental {
+		previous, err = LoadManifest(conf.DownloadPath)
+		if err != nil {
+			return nil, fmt.Errorf("error loading previous manifest: %s", err)
+		}
+	}
+
+	var crawlLog *CrawlLog
+	if conf.CrawlLog != "" {
+		crawlLog, err = OpenCrawlLog(conf.CrawlLog, conf.CrawlLogFormat)
+		if err != nil {
+			return nil, fmt.Errorf("error opening crawl log: %s", err)
+		}
+		observers = append(observers, crawlLog)
+	}
 
 	return &Scraper{
 		OldDomain:    conf.OldDomain,
 		NewDomain:    conf.NewDomain,
 		Roots:        conf.Roots,
+		Scope:        scope,
+		siteHost:     siteHost(host),
 		DownloadPath: conf.DownloadPath,
-		UseQueries:   conf.UseQueries,
+		UseQueries:   conf.UseQueries || conf.KeepParams != "",
+		UseCanonical: conf.UseCanonical,
+		Normalizer:   normalizer,
+
+		PortablePaths:   conf.PortablePaths,
+		RedirectStubs:   conf.RedirectStubs,
+		RedirectsFormat: conf.RedirectsFormat,
 
-		Scanning:    make(chan int, conf.Simultaneous), // Semaphore
-		NewLinks:    make(chan []Links, 100000),        // New links to scan
-		Pages:       make(chan Page, 100000),           // Pages scanned
-		Attachments: make(chan []string, 100000),       // Attachments
-		Started:     make(chan int, 100000),            // Crawls started
-		Finished:    make(chan int, 100000),            // Crawls finished
+		scanning: make(chan struct{}, conf.Simultaneous),
 
 		Indexed:    []string{},
 		ForSitemap: []string{},
 		Files:      []string{},
 		StartTime:  time.Now(),
 
-		Seen: make(map[string]bool),
+		Seen:      make(map[string]bool),
+		Redirects: make(map[string][]Redirect),
+
+		Duplicates: make(map[string][]string),
+		Manifest:   make(map[string]ManifestEntry),
 
-		Get: getter,
-		Con: con,
+		External:      conf.External || conf.ExternalAllow != "",
+		ExternalAllow: SplitList(conf.ExternalAllow),
+		ExternalDeny:  SplitList(conf.ExternalDeny),
+
+		Sanitize:  conf.Sanitize,
+		Sanitized: make(map[string]SanitizedPage),
+
+		Incremental: conf.Incremental,
+		Previous:    previous,
+
+		Check:         conf.Check || conf.CheckExternal,
+		CheckExternal: conf.CheckExternal,
+		CheckFormat:   conf.CheckFormat,
+		CheckOutput:   conf.CheckOutput,
+		Statuses:      make(map[string]LinkStatus),
+		Referrers:     make(map[string][]string),
+
+		Log:     crawlLog,
+		origins: make(map[string]origin),
+
+		Get:       getter,
+		observers: observers,
 	}, nil
 }
 
-// Close closes the channels
+// Close closes the crawl log
 func (s *Scraper) Close() {
-	close(s.Scanning)
-	close(s.NewLinks)
-	close(s.Pages)
-	close(s.Attachments)
-	close(s.Started)
-	close(s.Finished)
+	if s.Log != nil {
+		s.Log.Close()
+	}
 }
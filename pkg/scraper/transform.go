@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ErrNotHTML is the error of the documents of contents that are not HTML
+var ErrNotHTML = errors.New("not a HTML document")
+
+// Transformer changes the pages and files before they are saved
+type Transformer interface {
+	Transform(c *Content) error
+}
+
+// TransformerFunc is a func used as a transformer
+type TransformerFunc func(c *Content) error
+
+// Transform calls the func with the content
+func (f TransformerFunc) Transform(c *Content) error {
+	return f(c)
+}
+
+// Content of a page or file being saved. HTML pages can be changed as a
+// parsed document or as bytes, and the content is only parsed or rendered
+// again when switching from one to the other.
+type Content struct {
+	URL         string
+	ContentType string
+
+	doc  *html.Node
+	body []byte
+}
+
+// NewContent creates the content of the given URL
+func NewContent(link, contentType string, body []byte) *Content {
+	return &Content{URL: link, ContentType: contentType, body: body}
+}
+
+// MediaType returns the content type without its params, like text/html
+func (c *Content) MediaType() string {
+	media, _, err := mime.ParseMediaType(c.ContentType)
+	if err != nil {
+		media, _, _ = strings.Cut(c.ContentType, ";")
+	}
+
+	return strings.ToLower(strings.TrimSpace(media))
+}
+
+// IsHTML checks if the content is a HTML page
+func (c *Content) IsHTML() bool {
+	media := c.MediaType()
+	return media == "text/html" || media == "application/xhtml+xml"
+}
+
+// Document returns the parsed document of a HTML page. Changes on it are
+// saved.
+func (c *Content) Document() (*html.Node, error) {
+	if c.doc != nil {
+		return c.doc, nil
+	}
+
+	if !c.IsHTML() {
+		return nil, ErrNotHTML
+	}
+
+	doc, err := html.Parse(bytes.NewReader(c.body))
+	if err != nil {
+		return nil, err
+	}
+
+	c.doc, c.body = doc, nil
+	return doc, nil
+}
+
+// Bytes returns the content, rendering the document if it was parsed
+func (c *Content) Bytes() ([]byte, error) {
+	if c.doc != nil {
+		buf := new(bytes.Buffer)
+		if err := html.Render(buf, c.doc); err != nil {
+			return nil, err
+		}
+		c.doc, c.body = nil, buf.Bytes()
+	}
+
+	return c.body, nil
+}
+
+// SetBytes replaces the content
+func (c *Content) SetBytes(body []byte) {
+	c.doc, c.body = nil, body
+}
+
+// transform runs the transformers on the content of the given URL
+func (s *Scraper) transform(link, contentType string, body []byte) ([]byte, error) {
+	if len(s.Transformers) == 0 {
+		return body, nil
+	}
+
+	c := NewContent(link, contentType, body)
+	for _, t := range s.Transformers {
+		if err := t.Transform(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.Bytes()
+}
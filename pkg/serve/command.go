@@ -0,0 +1,82 @@
+package serve
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// Exit codes of the serve command
+const (
+	ExitOK    = 0
+	ExitUsage = 2
+	ExitError = 3
+)
+
+// Command runs the serve command with the given arguments, returning its exit
+// code once the server stops
+func Command(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	downloadPath := flags.String("path", "./website", "Local path the site was downloaded to")
+	addr := flags.String("addr", "localhost:8080", "Address to listen on")
+	compress := flags.Bool("gzip", false, "Compress the text responses for the clients accepting gzip")
+	notFound := flags.String("404", "", "HTML page to serve on the URLs missing from the mirror (default: 404.html of the download path, if any)")
+	domain := flags.String("u", "", "URL the site was downloaded from (default: taken from the manifest)")
+	portable := flags.Bool("portable", false, "The site was downloaded with -portable")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ./go-download-web serve [options]")
+		fmt.Fprintln(flags.Output(), "Serves a downloaded site on the same paths it was downloaded from.")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	mirror, err := scraper.OpenMirror(*downloadPath, *domain, *portable)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	server := &Server{Mirror: mirror, Gzip: *compress, Log: log.New(os.Stderr, "", log.LstdFlags)}
+
+	page := *notFound
+	if page == "" {
+		page = filepath.Join(*downloadPath, "404.html")
+		if _, err := os.Stat(page); err != nil {
+			page = ""
+		}
+	}
+	if page != "" {
+		server.NotFound, err = os.ReadFile(page)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitError
+		}
+	}
+
+	log.Printf("Serving %s on http://%s", mirror.Path, *addr)
+
+	httpServer := &http.Server{Addr: *addr, Handler: server, ReadHeaderTimeout: 10 * time.Second}
+	if err := httpServer.ListenAndServe(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	return ExitOK
+}
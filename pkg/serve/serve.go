@@ -0,0 +1,182 @@
+// Package serve serves a downloaded site locally, on the same paths it was
+// downloaded from, to check it before publishing it.
+package serve
+
+import (
+	"compress/gzip"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+)
+
+// Content types of the extensions usually found on a site, as the ones
+// known by the system may be missing or wrong
+var contentTypes = map[string]string{
+	".html":  "text/html; charset=utf-8",
+	".htm":   "text/html; charset=utf-8",
+	".css":   "text/css; charset=utf-8",
+	".js":    "text/javascript; charset=utf-8",
+	".mjs":   "text/javascript; charset=utf-8",
+	".json":  "application/json",
+	".xml":   "application/xml",
+	".txt":   "text/plain; charset=utf-8",
+	".svg":   "image/svg+xml",
+	".png":   "image/png",
+	".jpg":   "image/jpeg",
+	".jpeg":  "image/jpeg",
+	".gif":   "image/gif",
+	".webp":  "image/webp",
+	".avif":  "image/avif",
+	".ico":   "image/x-icon",
+	".woff":  "font/woff",
+	".woff2": "font/woff2",
+	".ttf":   "font/ttf",
+	".otf":   "font/otf",
+	".eot":   "application/vnd.ms-fontobject",
+	".pdf":   "application/pdf",
+	".mp3":   "audio/mpeg",
+	".mp4":   "video/mp4",
+	".webm":  "video/webm",
+	".wasm":  "application/wasm",
+	".zip":   "application/zip",
+}
+
+// Content types worth compressing, besides the text/* ones
+var compressible = []string{"application/json", "application/xml", "image/svg+xml", "text/javascript", "application/javascript"}
+
+// Server serves the files of a mirror
+type Server struct {
+	Mirror *scraper.Mirror
+
+	// Compress the text responses for the clients accepting gzip
+	Gzip bool
+
+	// Page served on the URLs missing from the mirror, if any
+	NotFound []byte
+
+	// Logger of the URLs missing from the mirror
+	Log *log.Logger
+}
+
+// ServeHTTP serves the file of the requested URL, or the 404 page if it is
+// not on the mirror
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name, ok := s.Mirror.Resolve(r.URL.RequestURI())
+	if !ok {
+		s.notFound(w, r)
+		return
+	}
+
+	// Serve the pages on their folder, so their relative links work
+	if path.Base(name) == "index.html" && !strings.HasSuffix(r.URL.Path, "/") && path.Base(r.URL.Path) != "index.html" {
+		target := r.URL.Path + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	f, err := os.Open(filepath.Join(s.Mirror.Path, filepath.FromSlash(name)))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	contentType := s.contentType(name)
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	if s.Gzip {
+		w.Header().Add("Vary", "Accept-Encoding")
+		if acceptsGzip(r) && isCompressible(contentType) {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.WriteHeader(http.StatusOK)
+			if r.Method == http.MethodHead {
+				return
+			}
+
+			gz := gzip.NewWriter(w)
+			io.Copy(gz, f)
+			gz.Close()
+			return
+		}
+	}
+
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// notFound serves the 404 page, logging the URL as missing from the mirror
+func (s *Server) notFound(w http.ResponseWriter, r *http.Request) {
+	if s.Log != nil {
+		referrer := r.Referer()
+		if referrer == "" {
+			referrer = "-"
+		}
+		s.Log.Printf("missing from mirror: %s (referrer: %s)", r.URL.RequestURI(), referrer)
+	}
+
+	if s.NotFound == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[".html"])
+	w.WriteHeader(http.StatusNotFound)
+	w.Write(s.NotFound)
+}
+
+// contentType returns the content type of the given file of the mirror: the
+// one it was downloaded with, or the one of its extension. It returns an
+// empty string if it is unknown, to be sniffed from the content.
+func (s *Server) contentType(name string) string {
+	if contentType := s.Mirror.ContentTypes[name]; contentType != "" {
+		return contentType
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+
+	return mime.TypeByExtension(ext)
+}
+
+// acceptsGzip checks if the client accepts gzip encoded responses
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		encoding, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.TrimSpace(encoding) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// isCompressible checks if the content type is worth compressing
+func isCompressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+	return strings.HasPrefix(mediaType, "text/") || scraper.IsInSlice(mediaType, compressible)
+}
@@ -0,0 +1,114 @@
+package serve_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/antsanchez/go-download-web/pkg/serve"
+	"github.com/stretchr/testify/assert"
+)
+
+func newServer(t *testing.T) (*serve.Server, *bytes.Buffer) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":       "<html><body>Home</body></html>",
+		"about/index.html": "<html><body>About</body></html>",
+		"css/style.css":    "body { color: red; }",
+		"fonts/font.woff2": "wOF2",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	m, err := scraper.OpenMirror(dir, "https://example.com", false)
+	assert.NoError(t, err)
+
+	logs := new(bytes.Buffer)
+	return &serve.Server{Mirror: m, Log: log.New(logs, "", 0)}, logs
+}
+
+func get(s *serve.Server, target string, header http.Header) *http.Response {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w.Result()
+}
+
+func TestServe(t *testing.T) {
+	s, logs := newServer(t)
+
+	tests := []struct {
+		target      string
+		status      int
+		contentType string
+	}{
+		{"/", http.StatusOK, "text/html; charset=utf-8"},
+		{"/about/", http.StatusOK, "text/html; charset=utf-8"},
+		{"/css/style.css", http.StatusOK, "text/css; charset=utf-8"},
+		{"/css/style.css?v=3", http.StatusOK, "text/css; charset=utf-8"},
+		{"/fonts/font.woff2", http.StatusOK, "font/woff2"},
+		{"/about", http.StatusMovedPermanently, ""},
+		{"/missing/", http.StatusNotFound, "text/plain; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		resp := get(s, tt.target, nil)
+		assert.Equal(t, tt.status, resp.StatusCode, tt.target)
+		if tt.contentType != "" {
+			assert.Equal(t, tt.contentType, resp.Header.Get("Content-Type"), tt.target)
+		}
+	}
+
+	resp := get(s, "/about", nil)
+	assert.Equal(t, "/about/", resp.Header.Get("Location"))
+
+	body, _ := io.ReadAll(get(s, "/about/", nil).Body)
+	assert.Equal(t, "<html><body>About</body></html>", string(body))
+
+	assert.Equal(t, "missing from mirror: /missing/ (referrer: -)\n", logs.String())
+}
+
+func TestServeNotFoundPage(t *testing.T) {
+	s, _ := newServer(t)
+	s.NotFound = []byte("<html><body>Not here</body></html>")
+
+	resp := get(s, "/missing/", http.Header{"Referer": []string{"http://localhost:8080/about/"}})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "<html><body>Not here</body></html>", string(body))
+}
+
+func TestServeGzip(t *testing.T) {
+	s, _ := newServer(t)
+	s.Gzip = true
+
+	resp := get(s, "/css/style.css", http.Header{"Accept-Encoding": []string{"gzip, deflate"}})
+	assert.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+
+	gz, err := gzip.NewReader(resp.Body)
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(gz)
+	assert.Equal(t, "body { color: red; }", string(body))
+
+	// Binary files and clients not accepting gzip get the file as it is
+	resp = get(s, "/fonts/font.woff2", http.Header{"Accept-Encoding": []string{"gzip"}})
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+
+	resp = get(s, "/css/style.css", http.Header{"Accept-Encoding": []string{"gzip;q=0"}})
+	assert.Empty(t, resp.Header.Get("Content-Encoding"))
+}
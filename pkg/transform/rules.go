@@ -0,0 +1,86 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"gopkg.in/yaml.v3"
+)
+
+// Rules file, with the rules to run in order
+type Rules struct {
+	Rules []Rule `yaml:"rules"`
+}
+
+// Rule of a rules file. Only one of remove, inject or replace must be set.
+type Rule struct {
+	// CSS selector of the elements to remove
+	Remove string `yaml:"remove"`
+
+	// Position to inject the HTML snippet on: head, body-start or body
+	Inject string `yaml:"inject"`
+	HTML   string `yaml:"html"`
+
+	// Regexp to replace with the replacement, on the files of the given
+	// content types, or on every text file
+	Replace string   `yaml:"replace"`
+	With    string   `yaml:"with"`
+	Types   []string `yaml:"types"`
+
+	// Regexp the URLs must match, if set
+	URLs string `yaml:"urls"`
+}
+
+// Load loads the transformers of the given rules file, in YAML or JSON
+func Load(name string) ([]scraper.Transformer, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules Rules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %s", name, err)
+	}
+
+	transformers := make([]scraper.Transformer, 0, len(rules.Rules))
+	for i, rule := range rules.Rules {
+		t, err := rule.Transformer()
+		if err != nil {
+			return nil, fmt.Errorf("error on rule %d of %s: %s", i+1, name, err)
+		}
+		transformers = append(transformers, t)
+	}
+
+	return transformers, nil
+}
+
+// Transformer returns the transformer of the rule
+func (r Rule) Transformer() (t scraper.Transformer, err error) {
+	set := 0
+	for _, action := range []string{r.Remove, r.Inject, r.Replace} {
+		if action != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, errors.New("must have one of remove, inject or replace")
+	}
+
+	switch {
+	case r.Remove != "":
+		t, err = Remove(r.Remove)
+	case r.Inject != "":
+		t, err = Inject(r.Inject, r.HTML)
+	default:
+		t, err = Replace(r.Replace, r.With, r.Types...)
+	}
+
+	if err != nil || r.URLs == "" {
+		return
+	}
+
+	return Only(r.URLs, t)
+}
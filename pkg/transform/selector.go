@@ -0,0 +1,327 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"golang.org/x/net/html"
+)
+
+// Selector is a list of CSS selectors, matching the elements matched by any
+// of them. Type, universal, id, class and attribute selectors are supported,
+// combined with the descendant and child combinators.
+type Selector []complexSelector
+
+// complexSelector is a list of compound selectors, the last one matching the
+// element itself and the others its ancestors
+type complexSelector []compoundSelector
+
+// compoundSelector matches a single element
+type compoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrSelector
+
+	// Must be the child of the element matched by the previous selector,
+	// instead of any descendant
+	child bool
+}
+
+// attrSelector matches an attribute, by its value if op is set
+type attrSelector struct {
+	key   string
+	op    string
+	value string
+}
+
+// ParseSelector parses a comma separated list of CSS selectors
+func ParseSelector(text string) (Selector, error) {
+	var sel Selector
+	p := &selectorParser{text: text}
+
+	for {
+		complex, err := p.complex()
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %s", text, err)
+		}
+		sel = append(sel, complex)
+
+		p.skipSpaces()
+		if p.done() {
+			return sel, nil
+		}
+		if p.text[p.pos] != ',' {
+			return nil, fmt.Errorf("invalid selector %q: unexpected %q", text, p.text[p.pos])
+		}
+		p.pos++
+	}
+}
+
+// Match checks if the node is an element matched by the selector
+func (sel Selector) Match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+
+	for _, complex := range sel {
+		if complex.match(n) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// match checks if the element and its ancestors are matched
+func (c complexSelector) match(n *html.Node) bool {
+	last := c[len(c)-1]
+	if !last.match(n) {
+		return false
+	}
+
+	return matchAncestors(c[:len(c)-1], last.child, n)
+}
+
+// matchAncestors checks if the ancestors of the element are matched by the
+// given selectors, the parent only if child is set
+func matchAncestors(c complexSelector, child bool, n *html.Node) bool {
+	if len(c) == 0 {
+		return true
+	}
+
+	last := c[len(c)-1]
+	for p := n.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+		if last.match(p) && matchAncestors(c[:len(c)-1], last.child, p) {
+			return true
+		}
+		if child {
+			break
+		}
+	}
+
+	return false
+}
+
+// match checks if the element is matched
+func (c compoundSelector) match(n *html.Node) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != n.Data {
+		return false
+	}
+
+	if c.id != "" && scraper.Attr(n, "id") != c.id {
+		return false
+	}
+
+	if len(c.classes) > 0 {
+		classes := strings.Fields(scraper.Attr(n, "class"))
+		for _, class := range c.classes {
+			if !scraper.IsInSlice(class, classes) {
+				return false
+			}
+		}
+	}
+
+	for _, a := range c.attrs {
+		if !a.match(n) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match checks if the element has the attribute
+func (a attrSelector) match(n *html.Node) bool {
+	for _, at := range n.Attr {
+		if at.Key != a.key {
+			continue
+		}
+
+		switch a.op {
+		case "":
+			return true
+		case "=":
+			return at.Val == a.value
+		case "~=":
+			return scraper.IsInSlice(a.value, strings.Fields(at.Val))
+		case "^=":
+			return a.value != "" && strings.HasPrefix(at.Val, a.value)
+		case "$=":
+			return a.value != "" && strings.HasSuffix(at.Val, a.value)
+		case "*=":
+			return a.value != "" && strings.Contains(at.Val, a.value)
+		}
+	}
+
+	return false
+}
+
+// selectorParser parses the selectors of a text
+type selectorParser struct {
+	text string
+	pos  int
+}
+
+func (p *selectorParser) done() bool {
+	return p.pos >= len(p.text)
+}
+
+func (p *selectorParser) skipSpaces() bool {
+	start := p.pos
+	for !p.done() && isSpace(p.text[p.pos]) {
+		p.pos++
+	}
+
+	return p.pos > start
+}
+
+// complex parses compound selectors until a comma or the end
+func (p *selectorParser) complex() (c complexSelector, err error) {
+	p.skipSpaces()
+
+	child := false
+	for {
+		compound, err := p.compound()
+		if err != nil {
+			return nil, err
+		}
+		compound.child = child
+		c = append(c, compound)
+
+		spaces := p.skipSpaces()
+		if p.done() || p.text[p.pos] == ',' {
+			return c, nil
+		}
+
+		child = p.text[p.pos] == '>'
+		if child {
+			p.pos++
+			p.skipSpaces()
+		} else if !spaces {
+			return nil, fmt.Errorf("unexpected %q", p.text[p.pos])
+		}
+	}
+}
+
+// compound parses a compound selector, like div.notice[role=alert]
+func (p *selectorParser) compound() (c compoundSelector, err error) {
+	start := p.pos
+
+	if !p.done() && p.text[p.pos] == '*' {
+		c.tag = "*"
+		p.pos++
+	} else if name := p.ident(); name != "" {
+		c.tag = strings.ToLower(name)
+	}
+
+	for !p.done() {
+		switch p.text[p.pos] {
+		case '#':
+			p.pos++
+			if c.id = p.ident(); c.id == "" {
+				return c, fmt.Errorf("missing id at %d", p.pos)
+			}
+		case '.':
+			p.pos++
+			class := p.ident()
+			if class == "" {
+				return c, fmt.Errorf("missing class at %d", p.pos)
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			p.pos++
+			a, err := p.attr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, a)
+		default:
+			if p.pos == start {
+				return c, fmt.Errorf("unexpected %q", p.text[p.pos])
+			}
+			return c, nil
+		}
+	}
+
+	if p.pos == start {
+		return c, fmt.Errorf("missing selector")
+	}
+
+	return c, nil
+}
+
+// attr parses an attribute selector, after its opening bracket
+func (p *selectorParser) attr() (a attrSelector, err error) {
+	p.skipSpaces()
+	if a.key = strings.ToLower(p.ident()); a.key == "" {
+		return a, fmt.Errorf("missing attribute at %d", p.pos)
+	}
+	p.skipSpaces()
+
+	for _, op := range []string{"=", "~=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.text[p.pos:], op) {
+			a.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+
+	if a.op != "" {
+		p.skipSpaces()
+		if a.value, err = p.value(); err != nil {
+			return
+		}
+		p.skipSpaces()
+	}
+
+	if p.done() || p.text[p.pos] != ']' {
+		return a, fmt.Errorf("missing ] at %d", p.pos)
+	}
+	p.pos++
+
+	return
+}
+
+// value parses the value of an attribute selector, quoted or not
+func (p *selectorParser) value() (string, error) {
+	if p.done() {
+		return "", fmt.Errorf("missing value at %d", p.pos)
+	}
+
+	quote := p.text[p.pos]
+	if quote != '"' && quote != '\'' {
+		if value := p.ident(); value != "" {
+			return value, nil
+		}
+		return "", fmt.Errorf("missing value at %d", p.pos)
+	}
+
+	end := strings.IndexByte(p.text[p.pos+1:], quote)
+	if end < 0 {
+		return "", fmt.Errorf("unclosed quote at %d", p.pos)
+	}
+
+	value := p.text[p.pos+1 : p.pos+1+end]
+	p.pos += end + 2
+	return value, nil
+}
+
+// ident parses a name, like the ones of tags, ids or classes
+func (p *selectorParser) ident() string {
+	start := p.pos
+	for !p.done() && isIdent(p.text[p.pos]) {
+		p.pos++
+	}
+
+	return p.text[start:p.pos]
+}
+
+func isIdent(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c >= 0x80
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
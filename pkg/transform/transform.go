@@ -0,0 +1,195 @@
+// Package transform has the built-in transformers of the pages and files
+// saved by the scraper, and the rules files configuring them
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Positions to inject snippets on
+const (
+	Head      = "head"
+	BodyStart = "body-start"
+	Body      = "body"
+)
+
+// Positions of the snippets
+var Positions = []string{Head, BodyStart, Body}
+
+// Remove removes the elements matched by the given CSS selector from the
+// HTML pages
+func Remove(selector string) (scraper.Transformer, error) {
+	sel, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	return scraper.TransformerFunc(func(c *scraper.Content) error {
+		if !c.IsHTML() {
+			return nil
+		}
+
+		doc, err := c.Document()
+		if err != nil {
+			return err
+		}
+
+		var matched []*html.Node
+		var f func(*html.Node)
+		f = func(n *html.Node) {
+			if sel.Match(n) {
+				matched = append(matched, n)
+				return
+			}
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				f(child)
+			}
+		}
+		f(doc)
+
+		for _, n := range matched {
+			n.Parent.RemoveChild(n)
+		}
+
+		return nil
+	}), nil
+}
+
+// Inject injects the given HTML snippet on the HTML pages: at the end of the
+// head, at the start of the body or at the end of the body
+func Inject(position, snippet string) (scraper.Transformer, error) {
+	var target atom.Atom
+	switch position {
+	case Head:
+		target = atom.Head
+	case BodyStart, Body:
+		target = atom.Body
+	default:
+		return nil, fmt.Errorf("invalid position %q (must be one of %s)", position, strings.Join(Positions, ", "))
+	}
+
+	return scraper.TransformerFunc(func(c *scraper.Content) error {
+		if !c.IsHTML() {
+			return nil
+		}
+
+		doc, err := c.Document()
+		if err != nil {
+			return err
+		}
+
+		parent := find(doc, target)
+		if parent == nil {
+			return nil
+		}
+
+		nodes, err := html.ParseFragment(strings.NewReader(snippet), parent)
+		if err != nil {
+			return err
+		}
+
+		first := parent.FirstChild
+		for _, n := range nodes {
+			if position == BodyStart {
+				parent.InsertBefore(n, first)
+			} else {
+				parent.AppendChild(n)
+			}
+		}
+
+		return nil
+	}), nil
+}
+
+// Replace replaces the matches of the given regexp with the replacement, on
+// the files of the given content types, or on every text file if none is
+// given. The replacement can use $1 or ${name} for the groups of the match.
+func Replace(pattern, replacement string, types ...string) (scraper.Transformer, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return scraper.TransformerFunc(func(c *scraper.Content) error {
+		if !matchType(c.MediaType(), types) {
+			return nil
+		}
+
+		body, err := c.Bytes()
+		if err != nil {
+			return err
+		}
+
+		c.SetBytes(re.ReplaceAll(body, []byte(replacement)))
+		return nil
+	}), nil
+}
+
+// Only runs the transformer on the URLs matching the given regexp
+func Only(pattern string, t scraper.Transformer) (scraper.Transformer, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return scraper.TransformerFunc(func(c *scraper.Content) error {
+		if !re.MatchString(c.URL) {
+			return nil
+		}
+
+		return t.Transform(c)
+	}), nil
+}
+
+// matchType checks if the media type is one of the given ones, or a text
+// type if none is given. A type ending with / matches every subtype.
+func matchType(media string, types []string) bool {
+	if len(types) == 0 {
+		return isText(media)
+	}
+
+	for _, t := range types {
+		t = strings.ToLower(t)
+		if media == t || strings.HasSuffix(t, "/") && strings.HasPrefix(media, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isText checks if the media type is a text one, that can be edited safely
+func isText(media string) bool {
+	if strings.HasPrefix(media, "text/") {
+		return true
+	}
+
+	for _, text := range []string{"javascript", "json", "xml", "svg"} {
+		if strings.Contains(media, text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// find returns the first element of the given type
+func find(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := find(child, a); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
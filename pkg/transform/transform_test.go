@@ -0,0 +1,179 @@
+package transform_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"github.com/antsanchez/go-download-web/pkg/transform"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+const page = `<html><head><title>Home</title></head><body>` +
+	`<div id="cookies" class="banner fixed">Accept?</div>` +
+	`<nav><ul><li class="ad">Ad</li><li>Home</li></ul></nav>` +
+	`<main><p class="ad">Inline ad</p><a href="/about/" data-track="1">About</a></main>` +
+	`<script>ga('create', 'UA-1234-1');</script>` +
+	`</body></html>`
+
+// run runs the transformers on the content, returning it
+func run(t *testing.T, link, contentType, body string, transformers ...scraper.Transformer) string {
+	c := scraper.NewContent(link, contentType, []byte(body))
+	for _, tr := range transformers {
+		assert.NoError(t, tr.Transform(c))
+	}
+
+	out, err := c.Bytes()
+	assert.NoError(t, err)
+	return string(out)
+}
+
+func TestSelector(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(page))
+	assert.NoError(t, err)
+
+	count := func(selector string) (n int) {
+		sel, err := transform.ParseSelector(selector)
+		assert.NoError(t, err, selector)
+
+		var f func(*html.Node)
+		f = func(node *html.Node) {
+			if sel.Match(node) {
+				n++
+			}
+			for c := node.FirstChild; c != nil; c = c.NextSibling {
+				f(c)
+			}
+		}
+		f(doc)
+		return
+	}
+
+	assert.Equal(t, 1, count("#cookies"))
+	assert.Equal(t, 1, count("div.banner.fixed"))
+	assert.Equal(t, 0, count("div.banner.hidden"))
+	assert.Equal(t, 2, count(".ad"))
+	assert.Equal(t, 1, count("nav .ad"))
+	assert.Equal(t, 1, count("ul > li.ad"))
+	assert.Equal(t, 0, count("nav > li"))
+	assert.Equal(t, 1, count("a[data-track]"))
+	assert.Equal(t, 1, count(`a[href^="/ab"]`))
+	assert.Equal(t, 1, count("[class~=fixed]"))
+	assert.Equal(t, 3, count("#cookies, main > *"))
+	assert.Equal(t, 9, count("body *"))
+
+	for _, invalid := range []string{"", "div,", "#", "a[href", "a[href=", "a:hover", `a[href="x]`} {
+		_, err := transform.ParseSelector(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	remove, err := transform.Remove("#cookies, .ad")
+	assert.NoError(t, err)
+
+	out := run(t, "https://example.com/", "text/html; charset=utf-8", page, remove)
+	assert.NotContains(t, out, "Accept?")
+	assert.NotContains(t, out, "ad</")
+	assert.Contains(t, out, "<li>Home</li>")
+
+	// Other files are not touched
+	css := ".ad { display: none }"
+	assert.Equal(t, css, run(t, "https://example.com/style.css", "text/css", css, remove))
+
+	_, err = transform.Remove("a:hover")
+	assert.Error(t, err)
+}
+
+func TestInject(t *testing.T) {
+	head, err := transform.Inject(transform.Head, `<meta name="robots" content="noindex">`)
+	assert.NoError(t, err)
+	start, err := transform.Inject(transform.BodyStart, `<div class="archived">Archived copy</div><hr>`)
+	assert.NoError(t, err)
+	end, err := transform.Inject(transform.Body, `<footer>Saved</footer>`)
+	assert.NoError(t, err)
+
+	out := run(t, "https://example.com/", "text/html", page, head, start, end)
+	assert.Contains(t, out, `<title>Home</title><meta name="robots" content="noindex"/></head>`)
+	assert.Contains(t, out, `<body><div class="archived">Archived copy</div><hr/><div id="cookies"`)
+	assert.Contains(t, out, `<footer>Saved</footer></body>`)
+
+	_, err = transform.Inject("footer", "<p></p>")
+	assert.Error(t, err)
+}
+
+func TestReplace(t *testing.T) {
+	replace, err := transform.Replace(`UA-\d+-(\d+)`, "UA-0000-$1")
+	assert.NoError(t, err)
+
+	assert.Contains(t, run(t, "https://example.com/", "text/html", page, replace), "UA-0000-1")
+	assert.Equal(t, "ga('UA-0000-2')", run(t, "https://example.com/a.js", "application/javascript", "ga('UA-99-2')", replace))
+
+	// Binary files are not touched, unless their type is given
+	assert.Equal(t, "UA-1-1", run(t, "https://example.com/a.png", "image/png", "UA-1-1", replace))
+
+	images, err := transform.Replace("UA", "XX", "image/")
+	assert.NoError(t, err)
+	assert.Equal(t, "XX-1-1", run(t, "https://example.com/a.png", "image/png", "UA-1-1", images))
+	assert.Equal(t, "UA-1-1", run(t, "https://example.com/a.css", "text/css", "UA-1-1", images))
+
+	// Documents changed and then replaced are rendered first
+	remove, err := transform.Remove("script")
+	assert.NoError(t, err)
+	out := run(t, "https://example.com/", "text/html", page, remove, replace)
+	assert.NotContains(t, out, "UA-")
+
+	_, err = transform.Replace("(", "")
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	rules := `
+rules:
+  - remove: "#cookies"
+  - inject: body-start
+    html: <p class="archived">Archived copy</p>
+  - replace: UA-\d+-\d+
+    with: UA-0000-0
+    urls: ^https://example\.com/blog/
+`
+	name := filepath.Join(t.TempDir(), "rules.yaml")
+	assert.NoError(t, os.WriteFile(name, []byte(rules), 0644))
+
+	transformers, err := transform.Load(name)
+	assert.NoError(t, err)
+	assert.Len(t, transformers, 3)
+
+	out := run(t, "https://example.com/", "text/html", page, transformers...)
+	assert.NotContains(t, out, "Accept?")
+	assert.Contains(t, out, `<body><p class="archived">Archived copy</p>`)
+	assert.Contains(t, out, "UA-1234-1")
+
+	out = run(t, "https://example.com/blog/post/", "text/html", page, transformers...)
+	assert.Contains(t, out, "UA-0000-0")
+
+	// JSON is valid too
+	json := filepath.Join(t.TempDir(), "rules.json")
+	assert.NoError(t, os.WriteFile(json, []byte(`{"rules": [{"remove": ".ad"}]}`), 0644))
+	transformers, err = transform.Load(json)
+	assert.NoError(t, err)
+	assert.Len(t, transformers, 1)
+
+	for _, invalid := range []string{
+		`rules: [{remove: "#a", inject: head}]`,
+		`rules: [{with: x}]`,
+		`rules: [{replace: "("}]`,
+		`rules: [{remove: "#a", urls: "("}]`,
+		`rules: {}`,
+	} {
+		assert.NoError(t, os.WriteFile(name, []byte(invalid), 0644))
+		_, err := transform.Load(name)
+		assert.Error(t, err, invalid)
+	}
+
+	_, err = transform.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
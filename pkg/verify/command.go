@@ -0,0 +1,83 @@
+package verify
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes of the verify command
+const (
+	ExitOK       = 0
+	ExitProblems = 1
+	ExitUsage    = 2
+	ExitError    = 3
+)
+
+// Command runs the verify command with the given arguments, returning its
+// exit code
+func Command(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	downloadPath := flags.String("path", "./website", "Local path the site was downloaded to")
+	oldDomain := flags.String("u", "", "URL the site was downloaded from (default: taken from the manifest)")
+	newDomain := flags.String("new", "", "New URL the site was downloaded for, if any")
+	portable := flags.Bool("portable", false, "The site was downloaded with -portable")
+	format := flags.String("format", "text", "Output format: text or json")
+	output := flags.String("o", "", "File to write the report to (default: standard output)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: ./go-download-web verify [options]")
+		fmt.Fprintln(flags.Output(), "Checks a downloaded site for references to missing files or to the old domain.")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	if *format != "text" && *format != "json" {
+		fmt.Fprintln(os.Stderr, "invalid format: -format (must be text or json)")
+		return ExitUsage
+	}
+
+	report, err := Verify(*downloadPath, Options{OldDomain: *oldDomain, NewDomain: *newDomain, Portable: *portable})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return ExitError
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "json" {
+		err = report.WriteJSON(w)
+	} else {
+		err = report.WriteText(w)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return ExitError
+	}
+
+	if report.HasProblems() {
+		return ExitProblems
+	}
+
+	return ExitOK
+}
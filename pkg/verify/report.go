@@ -0,0 +1,61 @@
+package verify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Report model, the result of verifying a mirror
+type Report struct {
+	// Domain the site was downloaded from, if known
+	Domain string `json:"domain,omitempty"`
+
+	// HTML and CSS files parsed, and references checked on them
+	Files      int `json:"files"`
+	References int `json:"references"`
+
+	Problems []Problem `json:"problems"`
+}
+
+// HasProblems checks if any reference doesn't work on the mirror
+func (r *Report) HasProblems() bool {
+	return len(r.Problems) > 0
+}
+
+// WriteText writes the problems of every file, and a summary
+func (r *Report) WriteText(w io.Writer) (err error) {
+	file := ""
+	for _, problem := range r.Problems {
+		if problem.File != file {
+			if file != "" {
+				fmt.Fprintln(w)
+			}
+			file = problem.File
+			if _, err = fmt.Fprintln(w, file); err != nil {
+				return
+			}
+		}
+		if _, err = fmt.Fprintf(w, "  %-10s  %s\n", problem.Kind, problem.Reference); err != nil {
+			return
+		}
+	}
+	if file != "" {
+		fmt.Fprintln(w)
+	}
+
+	_, err = fmt.Fprintf(w, "%d problems, %d references checked on %d files\n", len(r.Problems), r.References, r.Files)
+	return
+}
+
+// WriteJSON writes the report as an indented JSON object
+func (r *Report) WriteJSON(w io.Writer) error {
+	report := *r
+	if report.Problems == nil {
+		report.Problems = []Problem{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,268 @@
+// Package verify checks a downloaded site for references to files missing
+// from it, or to the domain it was downloaded from.
+package verify
+
+import (
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/antsanchez/go-download-web/pkg/scraper"
+	"golang.org/x/net/html"
+)
+
+// Kinds of problems found
+const (
+	// The reference points to a file missing from the mirror
+	Missing = "missing"
+
+	// The reference still points to the domain the site was downloaded from
+	OldDomain = "old-domain"
+)
+
+var (
+	// Attributes of the HTML elements holding references. The data of an
+	// object is one too, but not the action of a form, sent to the server.
+	linkAttributes = []string{"href", "src", "poster"}
+
+	// References on CSS, with url() or @import
+	urlsInCSS    = regexp.MustCompile(`url\(\s*['"]?([^'")]*?)['"]?\s*\)`)
+	importsInCSS = regexp.MustCompile(`@import\s+['"]([^'"]+)['"]`)
+
+	// Schemes of the references that are not files
+	ignoredSchemes = []string{"mailto", "tel", "javascript", "data", "about", "blob", "sms"}
+
+	// Files written by the scraper besides the site
+	ignoredFiles = []string{"manifest.json", "SHA256SUMS"}
+)
+
+// Problem model, a reference that doesn't work on the mirror
+type Problem struct {
+	// File with the reference, relative to the download path
+	File string `json:"file"`
+
+	Reference string `json:"reference"`
+	Kind      string `json:"kind"`
+}
+
+// Options of a verification
+type Options struct {
+	// Domain the site was downloaded from, taken from the manifest if empty
+	OldDomain string
+
+	// Domain the references were rewritten to, checked as local references
+	NewDomain string
+
+	// The site was downloaded with portable names
+	Portable bool
+}
+
+// Verify walks the site downloaded on the given path, parsing every HTML and
+// CSS file, and returns the references that point to files missing from the
+// mirror or to the old domain
+func Verify(downloadPath string, opts Options) (*Report, error) {
+	mirror, err := scraper.OpenMirror(downloadPath, opts.OldDomain, opts.Portable)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &verifier{mirror: mirror, report: &Report{Domain: mirror.Domain}}
+	if opts.NewDomain != "" {
+		v.newDomain, _ = url.Parse(scraper.RemoveLastSlash(opts.NewDomain))
+	}
+	if mirror.Domain != "" {
+		v.oldDomain, _ = url.Parse(mirror.Domain)
+	}
+
+	err = filepath.WalkDir(downloadPath, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(downloadPath, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if scraper.IsInSlice(rel, ignoredFiles) || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+
+		switch strings.ToLower(path.Ext(rel)) {
+		case ".html", ".htm":
+			return v.verifyFile(name, rel, htmlReferences)
+		case ".css":
+			return v.verifyFile(name, rel, cssReferences)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(v.report.Problems, func(i, j int) bool {
+		a, b := v.report.Problems[i], v.report.Problems[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Reference < b.Reference
+	})
+
+	return v.report, nil
+}
+
+// verifier checks the references of the files of a mirror
+type verifier struct {
+	mirror    *scraper.Mirror
+	oldDomain *url.URL
+	newDomain *url.URL
+	report    *Report
+}
+
+// verifyFile checks every reference of the given file, found with the given
+// func
+func (v *verifier) verifyFile(name, rel string, references func(string) []string) error {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	v.report.Files++
+
+	seen := make(map[string]bool)
+	for _, ref := range references(string(content)) {
+		ref = strings.TrimSpace(ref)
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		if kind := v.check(rel, ref); kind != "" {
+			v.report.Problems = append(v.report.Problems, Problem{File: rel, Reference: ref, Kind: kind})
+		}
+		v.report.References++
+	}
+
+	return nil
+}
+
+// check returns the kind of problem of a reference of the given file, or an
+// empty string if there is none
+func (v *verifier) check(file, ref string) string {
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return ""
+	}
+
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if scraper.IsInSlice(strings.ToLower(u.Scheme), ignoredSchemes) {
+		return ""
+	}
+
+	if u.Host != "" {
+		switch {
+		case onDomain(u, v.oldDomain):
+			return OldDomain
+		case onDomain(u, v.newDomain):
+			// Served on the new domain: check it as a reference to the mirror
+			u.Path = strings.TrimPrefix(u.Path, v.newDomain.Path)
+			u.Scheme, u.Host = "", ""
+		default:
+			// Other sites are not checked
+			return ""
+		}
+	}
+
+	// Relative references are resolved against the URL the file is served on
+	base := &url.URL{Path: "/" + file}
+	resolved := base.ResolveReference(u)
+	resolved.Fragment = ""
+
+	if _, ok := v.mirror.Resolve(resolved.RequestURI()); !ok {
+		return Missing
+	}
+	return ""
+}
+
+// onDomain checks if the URL is on the given domain, whatever its scheme
+func onDomain(u, domain *url.URL) bool {
+	if domain == nil || !strings.EqualFold(u.Host, domain.Host) {
+		return false
+	}
+
+	prefix := strings.TrimSuffix(domain.Path, "/")
+	return prefix == "" || u.Path == prefix || strings.HasPrefix(u.Path, prefix+"/")
+}
+
+// htmlReferences returns the references of an HTML document: its links,
+// sources and the URLs of its styles
+func htmlReferences(content string) (refs []string) {
+	doc, err := html.Parse(strings.NewReader(content))
+	if err != nil {
+		return
+	}
+
+	var f func(*html.Node)
+	f = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			// Resource hints point to other sites, not to files
+			skip := n.Data == "link" && scraper.HasRel(n, "preconnect", "dns-prefetch")
+
+			for _, a := range n.Attr {
+				switch {
+				case skip:
+				case scraper.IsInSlice(a.Key, linkAttributes), n.Data == "object" && a.Key == "data":
+					refs = append(refs, a.Val)
+				case a.Key == "srcset":
+					refs = append(refs, srcset(a.Val)...)
+				case a.Key == "style":
+					refs = append(refs, cssReferences(a.Val)...)
+				}
+			}
+
+			if n.Data == "style" && n.FirstChild != nil {
+				refs = append(refs, cssReferences(n.FirstChild.Data)...)
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			f(c)
+		}
+	}
+	f(doc)
+
+	return
+}
+
+// srcset returns the URLs of a srcset attribute
+func srcset(value string) (refs []string) {
+	for _, candidate := range strings.Split(value, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 {
+			refs = append(refs, fields[0])
+		}
+	}
+	return
+}
+
+// cssReferences returns the references of a stylesheet
+func cssReferences(content string) (refs []string) {
+	for _, match := range urlsInCSS.FindAllStringSubmatch(content, -1) {
+		refs = append(refs, match[1])
+	}
+	for _, match := range importsInCSS.FindAllStringSubmatch(content, -1) {
+		refs = append(refs, match[1])
+	}
+	return
+}
@@ -0,0 +1,71 @@
+package verify_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/antsanchez/go-download-web/pkg/verify"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestVerify(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"manifest.json": `[{"path": "index.html", "url": "https://example.com/"}]`,
+		"index.html": `<html><head><link rel="stylesheet" href="/css/style.css"><link rel="preconnect" href="https://example.com"></head>
+<body><a href="/about/">About</a><a href="about/#team">Team</a><a href="/gone/">Gone</a>
+<a href="http://example.com/old/">Old</a><a href="https://other.com/">Other</a><a href="mailto:me@example.com">Mail</a>
+<img src="/img/logo.png" srcset="/img/logo.png 1x, /img/logo@2x.png 2x"><div style="background: url('/img/bg.png')"></div>
+<object data="/media/intro.svg"></object><form action="/search"><input name="q"></form></body></html>`,
+		"about/index.html": `<html><body><a href="../">Home</a><a href="https://new.example.org/about/">New</a></body></html>`,
+		"css/style.css":    `@import "print.css"; body { background: url(../img/logo.png); } h1 { background: url("https://example.com/img/h1.png"); }`,
+		"img/logo.png":     "png",
+	})
+
+	report, err := verify.Verify(dir, verify.Options{NewDomain: "https://new.example.org"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com", report.Domain)
+	assert.Equal(t, 3, report.Files)
+	assert.True(t, report.HasProblems())
+	assert.Equal(t, []verify.Problem{
+		{File: "css/style.css", Reference: "https://example.com/img/h1.png", Kind: verify.OldDomain},
+		{File: "css/style.css", Reference: "print.css", Kind: verify.Missing},
+		{File: "index.html", Reference: "/gone/", Kind: verify.Missing},
+		{File: "index.html", Reference: "/img/bg.png", Kind: verify.Missing},
+		{File: "index.html", Reference: "/img/logo@2x.png", Kind: verify.Missing},
+		{File: "index.html", Reference: "/media/intro.svg", Kind: verify.Missing},
+		{File: "index.html", Reference: "http://example.com/old/", Kind: verify.OldDomain},
+	}, report.Problems)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, report.WriteText(buf))
+	assert.Contains(t, buf.String(), "css/style.css\n  old-domain  https://example.com/img/h1.png\n")
+	assert.Contains(t, buf.String(), "7 problems")
+}
+
+func TestVerifyClean(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"index.html":       `<html><body><a href="/about/">About</a><form action="/search"></form></body></html>`,
+		"about/index.html": `<html><body><a href="/">Home</a></body></html>`,
+	})
+
+	report, err := verify.Verify(dir, verify.Options{OldDomain: "https://example.com"})
+	assert.NoError(t, err)
+	assert.False(t, report.HasProblems())
+	assert.Equal(t, 2, report.References)
+
+	buf := new(bytes.Buffer)
+	assert.NoError(t, report.WriteJSON(buf))
+	assert.Contains(t, buf.String(), `"problems": []`)
+}